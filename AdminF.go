@@ -0,0 +1,93 @@
+// **********************************************************************
+// This file was generated by a TARS parser!
+// TARS version 3.2.2.2 by WSRD Tencent.
+// Generated from `AdminF.jce'
+// **********************************************************************
+
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+type AdminF interface {
+	Shutdown(context map[string]string) (map[string]string, error)
+	Notify(command string, context map[string]string) (string, map[string]string, error)
+}
+
+/* proxy for client */
+type AdminFProxy struct {
+	TarsClient *Client
+}
+
+func (p *AdminFProxy) Shutdown(context map[string]string) (respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "shutdown", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	return
+}
+func (p *AdminFProxy) Notify(command string, context map[string]string) (_ret string, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, command, 1)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "notify", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagStringValue(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+
+/* dispatcher for server */
+type AdminFDispatcher struct {
+	Impl AdminF
+}
+
+func (p *AdminFDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	reqBuffer := bytes.NewBuffer(req.SBuffer)
+	var osBuffer bytes.Buffer
+	var err error
+	switch req.SFuncName {
+	case "shutdown":
+		respContext, err := p.Impl.Shutdown(req.Context)
+		if nil != err {
+			return err
+		}
+		resp.Context = respContext
+	case "notify":
+		var command string
+		err = DecodeTagStringValue(reqBuffer, &command, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		_ret, respContext, err := p.Impl.Notify(command, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagStringValue(&osBuffer, _ret, 0)
+		resp.Context = respContext
+	default:
+		return NewTarsError(TarsServerNoFuncErr, "func mismatch:"+req.SFuncName)
+	}
+	resp.SBuffer = osBuffer.Bytes()
+	return nil
+}
+
+func NewAdminFProxy(obj string, timeout time.Duration, opts ...ClientOption) *AdminFProxy {
+	c := NewClient(obj, timeout, opts...)
+	proxy := &AdminFProxy{c}
+	return proxy
+}
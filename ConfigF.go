@@ -0,0 +1,527 @@
+// **********************************************************************
+// This file was generated by a TARS parser!
+// TARS version 3.2.2.2 by WSRD Tencent.
+// Generated from `ConfigF.jce'
+// **********************************************************************
+
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+type ConfigInfo struct {
+	Appname     string `tag:"0"  required:"true"  json:"appname"`
+	Servername  string `tag:"1"  required:"true"  json:"servername"`
+	Filename    string `tag:"2"  required:"true"  json:"filename"`
+	BAppOnly    bool   `tag:"3"  required:"true"  json:"bAppOnly"`
+	Host        string `tag:"4"  required:"false"  json:"host"`
+	Setdivision string `tag:"5"  required:"false"  json:"setdivision"`
+}
+
+func (p *ConfigInfo) ClassName() string {
+	return "tarsgo.ConfigInfo"
+}
+func (p *ConfigInfo) MD5() string {
+	return "4de6e30ec2ff65fecd02a6e276de1992"
+}
+func (p *ConfigInfo) ResetDefautlt() {
+	var empty ConfigInfo
+	*p = empty
+}
+func (p *ConfigInfo) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagStringValue(buf, p.Appname, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Servername, 1)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Filename, 2)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagBoolValue(buf, p.BAppOnly, 3)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Host, 4)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Setdivision, 5)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *ConfigInfo) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagStringValue(buf, &p.Appname, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Servername, 1, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Filename, 2, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagBoolValue(buf, &p.BAppOnly, 3, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Host, 4, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Setdivision, 5, false)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type GetConfigListInfo struct {
+	Appname       string `tag:"0"  required:"true"  json:"appname"`
+	Servername    string `tag:"1"  required:"false"  json:"servername"`
+	BAppOnly      bool   `tag:"2"  required:"false"  json:"bAppOnly"`
+	Host          string `tag:"3"  required:"false"  json:"host"`
+	Setdivision   string `tag:"4"  required:"false"  json:"setdivision"`
+	Containername string `tag:"5"  required:"false"  json:"containername"`
+}
+
+func (p *GetConfigListInfo) ClassName() string {
+	return "tarsgo.GetConfigListInfo"
+}
+func (p *GetConfigListInfo) MD5() string {
+	return "c6feb85231b17d5e71d70480823f0e2e"
+}
+func (p *GetConfigListInfo) ResetDefautlt() {
+	var empty GetConfigListInfo
+	*p = empty
+}
+func (p *GetConfigListInfo) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagStringValue(buf, p.Appname, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Servername, 1)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagBoolValue(buf, p.BAppOnly, 2)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Host, 3)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Setdivision, 4)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Containername, 5)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *GetConfigListInfo) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagStringValue(buf, &p.Appname, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Servername, 1, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagBoolValue(buf, &p.BAppOnly, 2, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Host, 3, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Setdivision, 4, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Containername, 5, false)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type ConfigF interface {
+	ListConfig(app string, server string, vf *[]string, context map[string]string) (int32, map[string]string, error)
+	LoadConfig(app string, server string, filename string, config *string, context map[string]string) (int32, map[string]string, error)
+	LoadConfigByHost(appServerName string, filename string, host string, config *string, context map[string]string) (int32, map[string]string, error)
+	CheckConfig(appServerName string, filename string, host string, result *string, context map[string]string) (int32, map[string]string, error)
+	ListConfigByInfo(configInfo ConfigInfo, vf *[]string, context map[string]string) (int32, map[string]string, error)
+	LoadConfigByInfo(configInfo ConfigInfo, config *string, context map[string]string) (int32, map[string]string, error)
+	CheckConfigByInfo(configInfo ConfigInfo, result *string, context map[string]string) (int32, map[string]string, error)
+	ListAllConfigByInfo(configInfo GetConfigListInfo, vf *[]string, context map[string]string) (int32, map[string]string, error)
+}
+
+/* proxy for client */
+type ConfigFProxy struct {
+	TarsClient *Client
+}
+
+func (p *ConfigFProxy) ListConfig(app string, server string, vf *[]string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, app, 1)
+	EncodeTagStringValue(&osBuffer, server, 2)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "ListConfig", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringsValue(respBuffer, vf, 3, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *ConfigFProxy) LoadConfig(app string, server string, filename string, config *string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, app, 1)
+	EncodeTagStringValue(&osBuffer, server, 2)
+	EncodeTagStringValue(&osBuffer, filename, 3)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "loadConfig", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringValue(respBuffer, config, 4, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *ConfigFProxy) LoadConfigByHost(appServerName string, filename string, host string, config *string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, appServerName, 1)
+	EncodeTagStringValue(&osBuffer, filename, 2)
+	EncodeTagStringValue(&osBuffer, host, 3)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "loadConfigByHost", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringValue(respBuffer, config, 4, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *ConfigFProxy) CheckConfig(appServerName string, filename string, host string, result *string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, appServerName, 1)
+	EncodeTagStringValue(&osBuffer, filename, 2)
+	EncodeTagStringValue(&osBuffer, host, 3)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "checkConfig", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringValue(respBuffer, result, 4, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *ConfigFProxy) ListConfigByInfo(configInfo ConfigInfo, vf *[]string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStructValue(&osBuffer, &configInfo, 1)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "ListConfigByInfo", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringsValue(respBuffer, vf, 2, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *ConfigFProxy) LoadConfigByInfo(configInfo ConfigInfo, config *string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStructValue(&osBuffer, &configInfo, 1)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "loadConfigByInfo", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringValue(respBuffer, config, 2, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *ConfigFProxy) CheckConfigByInfo(configInfo ConfigInfo, result *string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStructValue(&osBuffer, &configInfo, 1)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "checkConfigByInfo", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringValue(respBuffer, result, 2, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *ConfigFProxy) ListAllConfigByInfo(configInfo GetConfigListInfo, vf *[]string, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStructValue(&osBuffer, &configInfo, 1)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "ListAllConfigByInfo", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	tarsErr = DecodeTagStringsValue(respBuffer, vf, 2, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+
+/* dispatcher for server */
+type ConfigFDispatcher struct {
+	Impl ConfigF
+}
+
+func (p *ConfigFDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	reqBuffer := bytes.NewBuffer(req.SBuffer)
+	var osBuffer bytes.Buffer
+	var err error
+	switch req.SFuncName {
+	case "ListConfig":
+		var app string
+		var server string
+		err = DecodeTagStringValue(reqBuffer, &app, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &server, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var vf []string
+		_ret, respContext, err := p.Impl.ListConfig(app, server, &vf, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringsValue(&osBuffer, vf, 3)
+		resp.Context = respContext
+	case "loadConfig":
+		var app string
+		var server string
+		var filename string
+		err = DecodeTagStringValue(reqBuffer, &app, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &server, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &filename, 3, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var config string
+		_ret, respContext, err := p.Impl.LoadConfig(app, server, filename, &config, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringValue(&osBuffer, config, 4)
+		resp.Context = respContext
+	case "loadConfigByHost":
+		var appServerName string
+		var filename string
+		var host string
+		err = DecodeTagStringValue(reqBuffer, &appServerName, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &filename, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &host, 3, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var config string
+		_ret, respContext, err := p.Impl.LoadConfigByHost(appServerName, filename, host, &config, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringValue(&osBuffer, config, 4)
+		resp.Context = respContext
+	case "checkConfig":
+		var appServerName string
+		var filename string
+		var host string
+		err = DecodeTagStringValue(reqBuffer, &appServerName, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &filename, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &host, 3, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var result string
+		_ret, respContext, err := p.Impl.CheckConfig(appServerName, filename, host, &result, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringValue(&osBuffer, result, 4)
+		resp.Context = respContext
+	case "ListConfigByInfo":
+		var configInfo ConfigInfo
+		err = DecodeTagStructValue(reqBuffer, &configInfo, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var vf []string
+		_ret, respContext, err := p.Impl.ListConfigByInfo(configInfo, &vf, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringsValue(&osBuffer, vf, 2)
+		resp.Context = respContext
+	case "loadConfigByInfo":
+		var configInfo ConfigInfo
+		err = DecodeTagStructValue(reqBuffer, &configInfo, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var config string
+		_ret, respContext, err := p.Impl.LoadConfigByInfo(configInfo, &config, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringValue(&osBuffer, config, 2)
+		resp.Context = respContext
+	case "checkConfigByInfo":
+		var configInfo ConfigInfo
+		err = DecodeTagStructValue(reqBuffer, &configInfo, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var result string
+		_ret, respContext, err := p.Impl.CheckConfigByInfo(configInfo, &result, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringValue(&osBuffer, result, 2)
+		resp.Context = respContext
+	case "ListAllConfigByInfo":
+		var configInfo GetConfigListInfo
+		err = DecodeTagStructValue(reqBuffer, &configInfo, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var vf []string
+		_ret, respContext, err := p.Impl.ListAllConfigByInfo(configInfo, &vf, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagStringsValue(&osBuffer, vf, 2)
+		resp.Context = respContext
+	default:
+		return NewTarsError(TarsServerNoFuncErr, "func mismatch:"+req.SFuncName)
+	}
+	resp.SBuffer = osBuffer.Bytes()
+	return nil
+}
+
+func NewConfigFProxy(obj string, timeout time.Duration, opts ...ClientOption) *ConfigFProxy {
+	c := NewClient(obj, timeout, opts...)
+	proxy := &ConfigFProxy{c}
+	return proxy
+}
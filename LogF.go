@@ -0,0 +1,245 @@
+// **********************************************************************
+// This file was generated by a TARS parser!
+// TARS version 3.2.2.2 by WSRD Tencent.
+// Generated from `LogF.jce'
+// **********************************************************************
+
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+type LogInfo struct {
+	Appname           string `tag:"0"  required:"true"  json:"appname"`
+	Servername        string `tag:"1"  required:"true"  json:"servername"`
+	SFilename         string `tag:"2"  required:"true"  json:"sFilename"`
+	SFormat           string `tag:"3"  required:"true"  json:"sFormat"`
+	Setdivision       string `tag:"4"  required:"false"  json:"setdivision"`
+	BHasSufix         bool   `tag:"5"  required:"false"  json:"bHasSufix"`
+	BHasAppNamePrefix bool   `tag:"6"  required:"false"  json:"bHasAppNamePrefix"`
+	BHasSquareBracket bool   `tag:"7"  required:"false"  json:"bHasSquareBracket"`
+	SConcatStr        string `tag:"8"  required:"false"  json:"sConcatStr"`
+	SSepar            string `tag:"9"  required:"false"  json:"sSepar"`
+	SLogType          string `tag:"10"  required:"false"  json:"sLogType"`
+}
+
+func (p *LogInfo) ClassName() string {
+	return "tarsgo.LogInfo"
+}
+func (p *LogInfo) MD5() string {
+	return "4274e8eb5fc184ea5151f1c345c418e8"
+}
+func (p *LogInfo) ResetDefautlt() {
+	var empty LogInfo
+	*p = empty
+	p.BHasSufix = true
+	p.BHasAppNamePrefix = true
+	p.SConcatStr = "_"
+	p.SSepar = "|"
+}
+func (p *LogInfo) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagStringValue(buf, p.Appname, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Servername, 1)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SFilename, 2)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SFormat, 3)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Setdivision, 4)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagBoolValue(buf, p.BHasSufix, 5)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagBoolValue(buf, p.BHasAppNamePrefix, 6)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagBoolValue(buf, p.BHasSquareBracket, 7)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SConcatStr, 8)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SSepar, 9)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SLogType, 10)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *LogInfo) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagStringValue(buf, &p.Appname, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Servername, 1, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SFilename, 2, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SFormat, 3, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Setdivision, 4, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagBoolValue(buf, &p.BHasSufix, 5, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagBoolValue(buf, &p.BHasAppNamePrefix, 6, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagBoolValue(buf, &p.BHasSquareBracket, 7, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SConcatStr, 8, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SSepar, 9, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SLogType, 10, false)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type LogF interface {
+	Logger(app string, server string, file string, format string, buffer []string, context map[string]string) (map[string]string, error)
+	LoggerbyInfo(info LogInfo, buffer []string, context map[string]string) (map[string]string, error)
+}
+
+/* proxy for client */
+type LogFProxy struct {
+	TarsClient *Client
+}
+
+func (p *LogFProxy) Logger(app string, server string, file string, format string, buffer []string, context map[string]string) (respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, app, 1)
+	EncodeTagStringValue(&osBuffer, server, 2)
+	EncodeTagStringValue(&osBuffer, file, 3)
+	EncodeTagStringValue(&osBuffer, format, 4)
+	EncodeTagStringsValue(&osBuffer, buffer, 5)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "logger", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	return
+}
+func (p *LogFProxy) LoggerbyInfo(info LogInfo, buffer []string, context map[string]string) (respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStructValue(&osBuffer, &info, 1)
+	EncodeTagStringsValue(&osBuffer, buffer, 2)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "loggerbyInfo", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	return
+}
+
+/* dispatcher for server */
+type LogFDispatcher struct {
+	Impl LogF
+}
+
+func (p *LogFDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	reqBuffer := bytes.NewBuffer(req.SBuffer)
+	var osBuffer bytes.Buffer
+	var err error
+	switch req.SFuncName {
+	case "logger":
+		var app, server, file, format string
+		var buffer []string
+		err = DecodeTagStringValue(reqBuffer, &app, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &server, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &file, 3, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &format, 4, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringsValue(reqBuffer, &buffer, 5, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		respContext, err := p.Impl.Logger(app, server, file, format, buffer, req.Context)
+		if nil != err {
+			return err
+		}
+		resp.Context = respContext
+	case "loggerbyInfo":
+		var info LogInfo
+		var buffer []string
+		err = DecodeTagStructValue(reqBuffer, &info, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringsValue(reqBuffer, &buffer, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		respContext, err := p.Impl.LoggerbyInfo(info, buffer, req.Context)
+		if nil != err {
+			return err
+		}
+		resp.Context = respContext
+	default:
+		return NewTarsError(TarsServerNoFuncErr, "func mismatch:"+req.SFuncName)
+	}
+	resp.SBuffer = osBuffer.Bytes()
+	return nil
+}
+
+func NewLogFProxy(obj string, timeout time.Duration, opts ...ClientOption) *LogFProxy {
+	c := NewClient(obj, timeout, opts...)
+	proxy := &LogFProxy{c}
+	return proxy
+}
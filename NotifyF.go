@@ -0,0 +1,120 @@
+// **********************************************************************
+// This file was generated by a TARS parser!
+// TARS version 3.2.2.2 by WSRD Tencent.
+// Generated from `NotifyF.jce'
+// **********************************************************************
+
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+const (
+	NOTIFYNORMAL = int32(0)
+	NOTIFYWARN   = int32(1)
+	NOTIFYERROR  = int32(2)
+)
+
+type NotifyF interface {
+	ReportServer(sServerName string, sThreadId string, sResult string, context map[string]string) (map[string]string, error)
+	NotifyServer(sServerName string, level int32, sMessage string, context map[string]string) (map[string]string, error)
+}
+
+/* proxy for client */
+type NotifyFProxy struct {
+	TarsClient *Client
+}
+
+func (p *NotifyFProxy) ReportServer(sServerName string, sThreadId string, sResult string, context map[string]string) (respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, sServerName, 1)
+	EncodeTagStringValue(&osBuffer, sThreadId, 2)
+	EncodeTagStringValue(&osBuffer, sResult, 3)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "reportServer", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	return
+}
+func (p *NotifyFProxy) NotifyServer(sServerName string, level int32, sMessage string, context map[string]string) (respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagStringValue(&osBuffer, sServerName, 1)
+	EncodeTagInt32Value(&osBuffer, level, 2)
+	EncodeTagStringValue(&osBuffer, sMessage, 3)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "notifyServer", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	return
+}
+
+/* dispatcher for server */
+type NotifyFDispatcher struct {
+	Impl NotifyF
+}
+
+func (p *NotifyFDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	reqBuffer := bytes.NewBuffer(req.SBuffer)
+	var osBuffer bytes.Buffer
+	var err error
+	switch req.SFuncName {
+	case "reportServer":
+		var sServerName, sThreadId, sResult string
+		err = DecodeTagStringValue(reqBuffer, &sServerName, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &sThreadId, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &sResult, 3, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		respContext, err := p.Impl.ReportServer(sServerName, sThreadId, sResult, req.Context)
+		if nil != err {
+			return err
+		}
+		resp.Context = respContext
+	case "notifyServer":
+		var sServerName, sMessage string
+		var level int32
+		err = DecodeTagStringValue(reqBuffer, &sServerName, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagInt32Value(reqBuffer, &level, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &sMessage, 3, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		respContext, err := p.Impl.NotifyServer(sServerName, level, sMessage, req.Context)
+		if nil != err {
+			return err
+		}
+		resp.Context = respContext
+	default:
+		return NewTarsError(TarsServerNoFuncErr, "func mismatch:"+req.SFuncName)
+	}
+	resp.SBuffer = osBuffer.Bytes()
+	return nil
+}
+
+func NewNotifyFProxy(obj string, timeout time.Duration, opts ...ClientOption) *NotifyFProxy {
+	c := NewClient(obj, timeout, opts...)
+	proxy := &NotifyFProxy{c}
+	return proxy
+}
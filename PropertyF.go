@@ -0,0 +1,240 @@
+// **********************************************************************
+// This file was generated by a TARS parser!
+// TARS version 3.2.2.2 by WSRD Tencent.
+// Generated from `PropertyF.jce'
+// **********************************************************************
+
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+type StatPropMsgHead struct {
+	ModuleName   string `tag:"0"  required:"true"  json:"moduleName"`
+	Ip           string `tag:"1"  required:"true"  json:"ip"`
+	PropertyName string `tag:"2"  required:"true"  json:"propertyName"`
+	SetName      string `tag:"3"  required:"false"  json:"setName"`
+	SetArea      string `tag:"4"  required:"false"  json:"setArea"`
+	SetID        string `tag:"5"  required:"false"  json:"setID"`
+	SContainer   string `tag:"6"  required:"false"  json:"sContainer"`
+	IPropertyVer int32  `tag:"7"  required:"false"  json:"iPropertyVer"`
+}
+
+func (p *StatPropMsgHead) ClassName() string {
+	return "tarsgo.StatPropMsgHead"
+}
+func (p *StatPropMsgHead) MD5() string {
+	return "b883e8e03c91078ae251065222bdf1c4"
+}
+func (p *StatPropMsgHead) ResetDefautlt() {
+	var empty StatPropMsgHead
+	*p = empty
+}
+func (p *StatPropMsgHead) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagStringValue(buf, p.ModuleName, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Ip, 1)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.PropertyName, 2)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SetName, 3)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SetArea, 4)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SetID, 5)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SContainer, 6)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.IPropertyVer, 7)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *StatPropMsgHead) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagStringValue(buf, &p.ModuleName, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Ip, 1, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.PropertyName, 2, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SetName, 3, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SetArea, 4, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SetID, 5, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SContainer, 6, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.IPropertyVer, 7, false)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type StatPropInfo struct {
+	Policy string `tag:"0"  required:"true"  json:"policy"`
+	Value  string `tag:"1"  required:"true"  json:"value"`
+}
+
+func (p *StatPropInfo) ClassName() string {
+	return "tarsgo.StatPropInfo"
+}
+func (p *StatPropInfo) MD5() string {
+	return "b6437932480244f7fd4319c9ce44e6a4"
+}
+func (p *StatPropInfo) ResetDefautlt() {
+	var empty StatPropInfo
+	*p = empty
+}
+func (p *StatPropInfo) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagStringValue(buf, p.Policy, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.Value, 1)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *StatPropInfo) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagStringValue(buf, &p.Policy, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.Value, 1, true)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type StatPropMsgBody struct {
+	VInfo []StatPropInfo `tag:"0"  required:"true"  json:"vInfo"`
+}
+
+func (p *StatPropMsgBody) ClassName() string {
+	return "tarsgo.StatPropMsgBody"
+}
+func (p *StatPropMsgBody) MD5() string {
+	return "aaf4a8da64c39ad03e5312ae40ac523c"
+}
+func (p *StatPropMsgBody) ResetDefautlt() {
+	var empty StatPropMsgBody
+	*p = empty
+}
+func (p *StatPropMsgBody) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagVectorValue(buf, p.VInfo, 0)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *StatPropMsgBody) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagVectorValue(buf, &p.VInfo, 0, true)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type PropertyF interface {
+	ReportPropMsg(statmsg map[StatPropMsgHead]StatPropMsgBody, context map[string]string) (int32, map[string]string, error)
+}
+
+/* proxy for client */
+type PropertyFProxy struct {
+	TarsClient *Client
+}
+
+func (p *PropertyFProxy) ReportPropMsg(statmsg map[StatPropMsgHead]StatPropMsgBody, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagMapValue(&osBuffer, statmsg, 1)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "reportPropMsg", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+
+/* dispatcher for server */
+type PropertyFDispatcher struct {
+	Impl PropertyF
+}
+
+func (p *PropertyFDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	reqBuffer := bytes.NewBuffer(req.SBuffer)
+	var osBuffer bytes.Buffer
+	var err error
+	switch req.SFuncName {
+	case "reportPropMsg":
+		var statmsg map[StatPropMsgHead]StatPropMsgBody
+		err = DecodeTagMapValue(reqBuffer, &statmsg, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		_ret, respContext, err := p.Impl.ReportPropMsg(statmsg, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		resp.Context = respContext
+	default:
+		return NewTarsError(TarsServerNoFuncErr, "func mismatch:"+req.SFuncName)
+	}
+	resp.SBuffer = osBuffer.Bytes()
+	return nil
+}
+
+func NewPropertyFProxy(obj string, timeout time.Duration, opts ...ClientOption) *PropertyFProxy {
+	c := NewClient(obj, timeout, opts...)
+	proxy := &PropertyFProxy{c}
+	return proxy
+}
@@ -8,6 +8,7 @@ package tarsgo
 
 import (
 	"bytes"
+	"context"
 	"time"
 )
 
@@ -170,8 +171,86 @@ func (p *QueryFProxy) FindObjectByIdInSameSet(id string, setId string, activeEp
 	return
 }
 
-func NewQueryFProxy(obj string, timeout time.Duration) *QueryFProxy {
-	c := NewClient(obj, timeout)
+/* dispatcher for server */
+type QueryFDispatcher struct {
+	Impl QueryF
+}
+
+func (p *QueryFDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	reqBuffer := bytes.NewBuffer(req.SBuffer)
+	var osBuffer bytes.Buffer
+	var err error
+	switch req.SFuncName {
+	case "findObjectById":
+		var id string
+		err = DecodeTagStringValue(reqBuffer, &id, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		_ret, respContext, err := p.Impl.FindObjectById(id, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagVectorValue(&osBuffer, _ret, 0)
+		resp.Context = respContext
+	case "findObjectById4Any", "findObjectById4All", "findObjectByIdInSameGroup":
+		var id string
+		err = DecodeTagStringValue(reqBuffer, &id, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var activeEp, inactiveEp []EndpointF
+		var _ret int32
+		var respContext map[string]string
+		switch req.SFuncName {
+		case "findObjectById4Any":
+			_ret, respContext, err = p.Impl.FindObjectById4Any(id, &activeEp, &inactiveEp, req.Context)
+		case "findObjectById4All":
+			_ret, respContext, err = p.Impl.FindObjectById4All(id, &activeEp, &inactiveEp, req.Context)
+		default:
+			_ret, respContext, err = p.Impl.FindObjectByIdInSameGroup(id, &activeEp, &inactiveEp, req.Context)
+		}
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagVectorValue(&osBuffer, activeEp, 2)
+		EncodeTagVectorValue(&osBuffer, inactiveEp, 3)
+		resp.Context = respContext
+	case "findObjectByIdInSameStation", "findObjectByIdInSameSet":
+		var id, filter string
+		err = DecodeTagStringValue(reqBuffer, &id, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagStringValue(reqBuffer, &filter, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		var activeEp, inactiveEp []EndpointF
+		var _ret int32
+		var respContext map[string]string
+		if req.SFuncName == "findObjectByIdInSameStation" {
+			_ret, respContext, err = p.Impl.FindObjectByIdInSameStation(id, filter, &activeEp, &inactiveEp, req.Context)
+		} else {
+			_ret, respContext, err = p.Impl.FindObjectByIdInSameSet(id, filter, &activeEp, &inactiveEp, req.Context)
+		}
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		EncodeTagVectorValue(&osBuffer, activeEp, 3)
+		EncodeTagVectorValue(&osBuffer, inactiveEp, 4)
+		resp.Context = respContext
+	default:
+		return NewTarsError(TarsServerNoFuncErr, "func mismatch:"+req.SFuncName)
+	}
+	resp.SBuffer = osBuffer.Bytes()
+	return nil
+}
+
+func NewQueryFProxy(obj string, timeout time.Duration, opts ...ClientOption) *QueryFProxy {
+	c := NewClient(obj, timeout, opts...)
 	proxy := &QueryFProxy{c}
 	return proxy
 }
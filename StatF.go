@@ -0,0 +1,420 @@
+// **********************************************************************
+// This file was generated by a TARS parser!
+// TARS version 3.2.2.2 by WSRD Tencent.
+// Generated from `StatF.jce'
+// **********************************************************************
+
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+type StatMicMsgHead struct {
+	MasterName    string `tag:"0"  required:"true"  json:"masterName"`
+	SlaveName     string `tag:"1"  required:"true"  json:"slaveName"`
+	InterfaceName string `tag:"2"  required:"true"  json:"interfaceName"`
+	MasterIp      string `tag:"3"  required:"true"  json:"masterIp"`
+	SlaveIp       string `tag:"4"  required:"true"  json:"slaveIp"`
+	SlavePort     int32  `tag:"5"  required:"true"  json:"slavePort"`
+	ReturnValue   int32  `tag:"6"  required:"true"  json:"returnValue"`
+	SlaveSetName  string `tag:"7"  required:"false"  json:"slaveSetName"`
+	SlaveSetArea  string `tag:"8"  required:"false"  json:"slaveSetArea"`
+	SlaveSetID    string `tag:"9"  required:"false"  json:"slaveSetID"`
+	TarsVersion   string `tag:"10"  required:"false"  json:"tarsVersion"`
+}
+
+func (p *StatMicMsgHead) ClassName() string {
+	return "tarsgo.StatMicMsgHead"
+}
+func (p *StatMicMsgHead) MD5() string {
+	return "8220b3be929db53131dc7b771c2581ac"
+}
+func (p *StatMicMsgHead) ResetDefautlt() {
+	var empty StatMicMsgHead
+	*p = empty
+}
+func (p *StatMicMsgHead) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagStringValue(buf, p.MasterName, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SlaveName, 1)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.InterfaceName, 2)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.MasterIp, 3)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SlaveIp, 4)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.SlavePort, 5)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.ReturnValue, 6)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SlaveSetName, 7)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SlaveSetArea, 8)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SlaveSetID, 9)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.TarsVersion, 10)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *StatMicMsgHead) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagStringValue(buf, &p.MasterName, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SlaveName, 1, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.InterfaceName, 2, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.MasterIp, 3, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SlaveIp, 4, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.SlavePort, 5, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.ReturnValue, 6, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SlaveSetName, 7, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SlaveSetArea, 8, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SlaveSetID, 9, false)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.TarsVersion, 10, false)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type StatMicMsgBody struct {
+	Count         int32           `tag:"0"  required:"true"  json:"count"`
+	TimeoutCount  int32           `tag:"1"  required:"true"  json:"timeoutCount"`
+	ExecCount     int32           `tag:"2"  required:"true"  json:"execCount"`
+	IntervalCount map[int32]int32 `tag:"3"  required:"true"  json:"intervalCount"`
+	TotalRspTime  int64           `tag:"4"  required:"true"  json:"totalRspTime"`
+	MaxRspTime    int32           `tag:"5"  required:"true"  json:"maxRspTime"`
+	MinRspTime    int32           `tag:"6"  required:"true"  json:"minRspTime"`
+}
+
+func (p *StatMicMsgBody) ClassName() string {
+	return "tarsgo.StatMicMsgBody"
+}
+func (p *StatMicMsgBody) MD5() string {
+	return "a4258486d63e9c8e843cfac2fa3ee577"
+}
+func (p *StatMicMsgBody) ResetDefautlt() {
+	var empty StatMicMsgBody
+	*p = empty
+}
+func (p *StatMicMsgBody) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagInt32Value(buf, p.Count, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.TimeoutCount, 1)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.ExecCount, 2)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagMapValue(buf, p.IntervalCount, 3)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt64Value(buf, p.TotalRspTime, 4)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.MaxRspTime, 5)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.MinRspTime, 6)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *StatMicMsgBody) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagInt32Value(buf, &p.Count, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.TimeoutCount, 1, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.ExecCount, 2, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagMapValue(buf, &p.IntervalCount, 3, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt64Value(buf, &p.TotalRspTime, 4, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.MaxRspTime, 5, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.MinRspTime, 6, true)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type StatSampleMsg struct {
+	Unid          string `tag:"0"  required:"true"  json:"unid"`
+	MasterName    string `tag:"1"  required:"true"  json:"masterName"`
+	SlaveName     string `tag:"2"  required:"true"  json:"slaveName"`
+	InterfaceName string `tag:"3"  required:"true"  json:"interfaceName"`
+	MasterIp      string `tag:"4"  required:"true"  json:"masterIp"`
+	SlaveIp       string `tag:"5"  required:"true"  json:"slaveIp"`
+	Depth         int32  `tag:"6"  required:"true"  json:"depth"`
+	Width         int32  `tag:"7"  required:"true"  json:"width"`
+	ParentWidth   int32  `tag:"8"  required:"true"  json:"parentWidth"`
+}
+
+func (p *StatSampleMsg) ClassName() string {
+	return "tarsgo.StatSampleMsg"
+}
+func (p *StatSampleMsg) MD5() string {
+	return "832c185be243615186411dd7b57b6318"
+}
+func (p *StatSampleMsg) ResetDefautlt() {
+	var empty StatSampleMsg
+	*p = empty
+}
+func (p *StatSampleMsg) Encode(buf *bytes.Buffer) error {
+	var err error
+	err = EncodeTagStringValue(buf, p.Unid, 0)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.MasterName, 1)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SlaveName, 2)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.InterfaceName, 3)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.MasterIp, 4)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagStringValue(buf, p.SlaveIp, 5)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.Depth, 6)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.Width, 7)
+	if nil != err {
+		return err
+	}
+	err = EncodeTagInt32Value(buf, p.ParentWidth, 8)
+	if nil != err {
+		return err
+	}
+	return nil
+}
+func (p *StatSampleMsg) Decode(buf *bytes.Buffer) error {
+	var err error
+	err = DecodeTagStringValue(buf, &p.Unid, 0, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.MasterName, 1, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SlaveName, 2, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.InterfaceName, 3, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.MasterIp, 4, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagStringValue(buf, &p.SlaveIp, 5, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.Depth, 6, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.Width, 7, true)
+	if nil != err {
+		return err
+	}
+	err = DecodeTagInt32Value(buf, &p.ParentWidth, 8, true)
+	if nil != err {
+		return err
+	}
+	return err
+}
+
+type StatF interface {
+	ReportMicMsg(msg map[StatMicMsgHead]StatMicMsgBody, bFromClient bool, context map[string]string) (int32, map[string]string, error)
+	ReportSampleMsg(msg []StatSampleMsg, context map[string]string) (int32, map[string]string, error)
+}
+
+/* proxy for client */
+type StatFProxy struct {
+	TarsClient *Client
+}
+
+func (p *StatFProxy) ReportMicMsg(msg map[StatMicMsgHead]StatMicMsgBody, bFromClient bool, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagMapValue(&osBuffer, msg, 1)
+	EncodeTagBoolValue(&osBuffer, bFromClient, 2)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "reportMicMsg", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+func (p *StatFProxy) ReportSampleMsg(msg []StatSampleMsg, context map[string]string) (_ret int32, respContext map[string]string, tarsErr error) {
+	var osBuffer bytes.Buffer
+	EncodeTagVectorValue(&osBuffer, msg, 1)
+	rep, err := p.TarsClient.Invoke(JCENORMAL, "reportSampleMsg", &osBuffer, context)
+	if nil != err {
+		tarsErr = err
+		return
+	}
+
+	respContext = rep.Context
+	respBuffer := bytes.NewBuffer(rep.SBuffer)
+	tarsErr = DecodeTagInt32Value(respBuffer, &_ret, 0, true)
+	if nil != tarsErr {
+		return
+	}
+	return
+}
+
+/* dispatcher for server */
+type StatFDispatcher struct {
+	Impl StatF
+}
+
+func (p *StatFDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	reqBuffer := bytes.NewBuffer(req.SBuffer)
+	var osBuffer bytes.Buffer
+	var err error
+	switch req.SFuncName {
+	case "reportMicMsg":
+		var msg map[StatMicMsgHead]StatMicMsgBody
+		var bFromClient bool
+		err = DecodeTagMapValue(reqBuffer, &msg, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		err = DecodeTagBoolValue(reqBuffer, &bFromClient, 2, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		_ret, respContext, err := p.Impl.ReportMicMsg(msg, bFromClient, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		resp.Context = respContext
+	case "reportSampleMsg":
+		var msg []StatSampleMsg
+		err = DecodeTagVectorValue(reqBuffer, &msg, 1, true)
+		if nil != err {
+			return NewTarsError(TarsServerDecodeErr, err.Error())
+		}
+		_ret, respContext, err := p.Impl.ReportSampleMsg(msg, req.Context)
+		if nil != err {
+			return err
+		}
+		EncodeTagInt32Value(&osBuffer, _ret, 0)
+		resp.Context = respContext
+	default:
+		return NewTarsError(TarsServerNoFuncErr, "func mismatch:"+req.SFuncName)
+	}
+	resp.SBuffer = osBuffer.Bytes()
+	return nil
+}
+
+func NewStatFProxy(obj string, timeout time.Duration, opts ...ClientOption) *StatFProxy {
+	c := NewClient(obj, timeout, opts...)
+	proxy := &StatFProxy{c}
+	return proxy
+}
@@ -0,0 +1,230 @@
+package tarsgo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdminServant is the servant TARS nodes send commands to, on the local endpoint of the
+// server config.
+const AdminServant = "AdminObj"
+
+var ErrUnknownAdminCommand = errors.New("Unknown admin command")
+
+// AdminHandler runs an admin command. params is what follows the command, such as "DEBUG"
+// in "tars.setloglevel DEBUG"; the result is sent back to whoever sent the command.
+type AdminHandler func(command, params string) (string, error)
+
+// Admin implements AdminF, the commands TARS nodes send to every server. Applications
+// add their own commands with Register. Results are also reported to the notify servant
+// of the server config, if it has one.
+type Admin struct {
+	app      *Application
+	started  time.Time
+	mutex    sync.Mutex
+	handlers map[string]AdminHandler
+	notify   *NotifyFProxy
+	pprofs   map[*http.Server]*time.Timer
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func newAdmin(app *Application) *Admin {
+	a := &Admin{
+		app:      app,
+		started:  time.Now(),
+		handlers: make(map[string]AdminHandler),
+		pprofs:   make(map[*http.Server]*time.Timer),
+		stop:     make(chan struct{}),
+	}
+	a.Register("tars.help", a.help)
+	a.Register("tars.viewstatus", a.viewStatus)
+	a.Register("tars.setloglevel", a.setLogLevel)
+	a.Register("tars.loadconfig", a.loadConfig)
+	a.Register("tars.connection", a.connection)
+	a.Register("tars.pprof", a.pprof)
+	return a
+}
+
+// Register runs h for command, replacing the handler it had.
+func (a *Admin) Register(command string, h AdminHandler) {
+	a.mutex.Lock()
+	a.handlers[command] = h
+	a.mutex.Unlock()
+}
+
+func (a *Admin) Notify(command string, context map[string]string) (string, map[string]string, error) {
+	command = strings.TrimSpace(command)
+	name, params, _ := strings.Cut(command, " ")
+	a.mutex.Lock()
+	h, exist := a.handlers[name]
+	a.mutex.Unlock()
+	if !exist {
+		return "", nil, fmt.Errorf("%w:%s", ErrUnknownAdminCommand, name)
+	}
+	result, err := h(name, strings.TrimSpace(params))
+	if nil != err {
+		logMsg(LogLevelError, "Admin command failed", Field{"command", command}, Field{FieldError, err})
+		a.report(command + " failed:" + err.Error())
+		return "", nil, err
+	}
+	logMsg(LogLevelInfo, "Admin command done", Field{"command", command})
+	a.report(result)
+	return result, nil, nil
+}
+
+// report sends the result of a command to the notify servant in the background, so that
+// the command returns without waiting for it.
+func (a *Admin) report(result string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	notify := a.notify
+	if nil == notify {
+		return
+	}
+	// started under the mutex, so that close waits for it when closing the client
+	notify.TarsClient.goroutine(func() {
+		if _, err := notify.ReportServer(a.app.Name(), strconv.Itoa(os.Getpid()), result, nil); nil != err {
+			logMsg(LogLevelError, "Failed to report admin result", Field{FieldError, err})
+		}
+	})
+}
+
+// setNotify makes the results go to the notify servant obj.
+func (a *Admin) setNotify(obj string) {
+	a.mutex.Lock()
+	a.notify = NewNotifyFProxy(obj, 3*time.Second)
+	a.mutex.Unlock()
+}
+
+// close stops the pprof servers and the client of the notify servant.
+func (a *Admin) close() error {
+	a.mutex.Lock()
+	pprofs, notify := a.pprofs, a.notify
+	a.pprofs = make(map[*http.Server]*time.Timer)
+	a.notify = nil
+	a.mutex.Unlock()
+	for server, timer := range pprofs {
+		timer.Stop()
+		server.Close()
+	}
+	if nil != notify {
+		notify.TarsClient.Close()
+	}
+	return nil
+}
+
+// Shutdown makes Application.Run return.
+func (a *Admin) Shutdown(context map[string]string) (map[string]string, error) {
+	logMsg(LogLevelInfo, "Shutdown by admin")
+	a.stopOnce.Do(func() { close(a.stop) })
+	return nil, nil
+}
+
+func (a *Admin) help(command, params string) (string, error) {
+	a.mutex.Lock()
+	commands := make([]string, 0, len(a.handlers))
+	for name := range a.handlers {
+		commands = append(commands, name)
+	}
+	a.mutex.Unlock()
+	sort.Strings(commands)
+	return strings.Join(commands, "\n") + "\n", nil
+}
+
+func (a *Admin) viewStatus(command, params string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "server:%s\n", a.app.Name())
+	fmt.Fprintf(&b, "pid:%d\n", os.Getpid())
+	fmt.Fprintf(&b, "uptime:%s\n", time.Since(a.started).Round(time.Second))
+	fmt.Fprintf(&b, "goroutines:%d\n", runtime.NumGoroutine())
+	fmt.Fprintf(&b, "loglevel:%s\n", GetLogLevel())
+	if setDivision := a.app.Config.SetDivision; setDivision != "" {
+		fmt.Fprintf(&b, "setdivision:%s\n", setDivision)
+	}
+	for _, adapter := range a.app.Config.Server.Adapters {
+		fmt.Fprintf(&b, "adapter:%s servant:%s endpoint:%s threads:%d maxconns:%d queuecap:%d\n",
+			adapter.Name, adapter.Servant, adapter.Endpoint, adapter.Threads, adapter.MaxConns, adapter.QueueCap)
+	}
+	return b.String(), nil
+}
+
+func (a *Admin) setLogLevel(command, params string) (string, error) {
+	level, err := ParseLogLevel(params)
+	if nil != err {
+		return "", err
+	}
+	SetLogLevel(level)
+	return "set log level [" + level.String() + "] ok", nil
+}
+
+func (a *Admin) loadConfig(command, params string) (string, error) {
+	if nil == a.app.RemoteConfig {
+		return "", errors.New("No config servant in the server config")
+	}
+	if _, err := a.app.RemoteConfig.Reload(params); nil != err {
+		return "", err
+	}
+	return "loaded config " + params, nil
+}
+
+func (a *Admin) connection(command, params string) (string, error) {
+	conns := a.app.Server.connections()
+	var b strings.Builder
+	for _, adapter := range a.app.Config.Server.Adapters {
+		remotes := conns[a.app.listenAddr(adapter.Name)]
+		fmt.Fprintf(&b, "adapter:%s connections:%d\n", adapter.Name, len(remotes))
+		for _, remote := range remotes {
+			b.WriteString("  " + remote + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// pprof serves net/http/pprof for a while: "tars.pprof [addr] [seconds]" listens on addr,
+// 127.0.0.1 on any port by default, for seconds, 600 by default.
+func (a *Admin) pprof(command, params string) (string, error) {
+	fields := strings.Fields(params)
+	addr, seconds := "127.0.0.1:0", 600
+	if len(fields) > 0 {
+		addr = fields[0]
+	}
+	if len(fields) > 1 {
+		n, err := strconv.Atoi(fields[1])
+		if nil != err || n <= 0 {
+			return "", fmt.Errorf("Invalid pprof duration:%s", fields[1])
+		}
+		seconds = n
+	}
+	l, err := net.Listen("tcp", addr)
+	if nil != err {
+		return "", err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	a.mutex.Lock()
+	a.pprofs[server] = time.AfterFunc(time.Duration(seconds)*time.Second, func() {
+		a.mutex.Lock()
+		delete(a.pprofs, server)
+		a.mutex.Unlock()
+		server.Close()
+	})
+	a.mutex.Unlock()
+	return fmt.Sprintf("pprof listening on http://%s/debug/pprof/ for %ds", l.Addr(), seconds), nil
+}
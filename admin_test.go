@@ -0,0 +1,178 @@
+package tarsgo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// notifyRecorder keeps the results servers report to NotifyObj.
+type notifyRecorder struct {
+	mutex   sync.Mutex
+	results []string
+	// hold, when set, delays the reports until it is closed.
+	hold chan struct{}
+}
+
+func (r *notifyRecorder) ReportServer(sServerName string, sThreadId string, sResult string, context map[string]string) (map[string]string, error) {
+	if nil != r.hold {
+		<-r.hold
+	}
+	r.mutex.Lock()
+	r.results = append(r.results, sServerName+":"+sResult)
+	r.mutex.Unlock()
+	return nil, nil
+}
+
+func (r *notifyRecorder) NotifyServer(sServerName string, level int32, sMessage string, context map[string]string) (map[string]string, error) {
+	return nil, nil
+}
+
+func TestAdmin(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+
+	store := &configStore{files: map[string]string{"app.conf": "<app>\n</app>\n"}}
+	_, configObj := startTestServer(t, "tars.tarsconfig.ConfigObj", &ConfigFDispatcher{store})
+	recorder := &notifyRecorder{hold: make(chan struct{})}
+	var released sync.Once
+	release := func() { released.Do(func() { close(recorder.hold) }) }
+	defer release()
+	_, notifyObj := startTestServer(t, "tars.tarsnotify.NotifyObj", &NotifyFDispatcher{recorder})
+	echoPort, adminPort := freePort(t), freePort(t)
+	local := fmt.Sprintf("tcp -h 127.0.0.1 -p %d", adminPort)
+	dir := t.TempDir()
+	app := NewApplication(&ApplicationConfig{Server: ServerConfig{
+		App:      "Test",
+		Server:   "EchoServer",
+		Local:    local,
+		BasePath: dir,
+		Config:   configObj,
+		Notify:   notifyObj,
+		Adapters: []AdapterConfig{{
+			Name:     "Test.EchoServer.EchoObjAdapter",
+			Endpoint: fmt.Sprintf("tcp -h 0.0.0.0 -p %d", echoPort),
+			Servant:  "Test.EchoServer.EchoObj",
+		}},
+	}})
+	app.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	app.Admin.Register("echo", func(command, params string) (string, error) {
+		return command + ":" + params, nil
+	})
+	done := make(chan error, 1)
+	go func() { done <- app.Run() }()
+
+	admin := NewAdminFProxy(AdminServant+"@"+local, time.Second)
+	defer admin.TarsClient.Close()
+	notify := func(command string) (string, error) {
+		result, _, err := admin.Notify(command, nil)
+		return result, err
+	}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if _, err := notify("tars.help"); nil == err {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+	}
+
+	if result, err := notify("tars.setloglevel DEBUG"); nil != err || GetLogLevel() != LogLevelDebug {
+		t.Fatalf("setloglevel returned %q, %v", result, err)
+	}
+	if _, err := notify("tars.setloglevel LOUD"); nil == err {
+		t.Fatal("set an invalid log level")
+	}
+	if result, err := notify("tars.viewstatus"); nil != err || !strings.Contains(result, "server:Test.EchoServer\n") || !strings.Contains(result, "loglevel:DEBUG\n") {
+		t.Fatalf("viewstatus returned %q, %v", result, err)
+	}
+	// commands do not wait for their results to be reported
+	if result, err := notify("echo  hello world "); nil != err || result != "echo:hello world" {
+		t.Fatalf("custom command returned %q, %v", result, err)
+	}
+	release()
+	waitFor(t, "reported result", func() bool {
+		recorder.mutex.Lock()
+		defer recorder.mutex.Unlock()
+		for _, result := range recorder.results {
+			if result == "Test.EchoServer:echo:hello world" {
+				return true
+			}
+		}
+		return false
+	})
+	if _, err := notify("tars.missing"); nil == err {
+		t.Fatal("unknown command succeeded")
+	}
+
+	c := NewClient(fmt.Sprintf("Test.EchoServer.EchoObj@tcp -h 127.0.0.1 -p %d", echoPort), time.Second)
+	defer c.Close()
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+		t.Fatal(err)
+	}
+	if result, err := notify("tars.connection"); nil != err || !strings.Contains(result, "adapter:Test.EchoServer.EchoObjAdapter connections:1\n") {
+		t.Fatalf("connection returned %q, %v", result, err)
+	}
+
+	var mutex sync.Mutex
+	var reloaded []string
+	app.RemoteConfig.Subscribe(func(filename, content string) {
+		mutex.Lock()
+		reloaded = append(reloaded, filename)
+		mutex.Unlock()
+	})
+	store.set("app.conf", "<app>\nkey=value\n</app>\n")
+	if _, err := notify("tars.loadconfig app.conf"); nil != err {
+		t.Fatal(err)
+	}
+	mutex.Lock()
+	if len(reloaded) != 1 || reloaded[0] != "app.conf" {
+		t.Fatalf("unexpected reloads %v", reloaded)
+	}
+	mutex.Unlock()
+	if b, err := os.ReadFile(filepath.Join(dir, "app.conf")); nil != err || !strings.Contains(string(b), "key=value") {
+		t.Fatalf("unexpected config %q, %v", b, err)
+	}
+	if _, err := notify("tars.loadconfig missing.conf"); nil == err {
+		t.Fatal("loaded a missing config")
+	}
+
+	result, err := notify("tars.pprof 127.0.0.1:0 5")
+	if nil != err {
+		t.Fatal(err)
+	}
+	i := strings.Index(result, "http://")
+	if i < 0 {
+		t.Fatalf("unexpected pprof result %q", result)
+	}
+	pprofURL := strings.Fields(result[i:])[0]
+	resp, err := http.Get(pprofURL + "cmdline")
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected pprof status %d", resp.StatusCode)
+	}
+
+	if _, err := admin.Shutdown(nil); nil != err {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if nil != err {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+	http.DefaultClient.CloseIdleConnections()
+	if resp, err = http.Get(pprofURL + "cmdline"); nil == err {
+		resp.Body.Close()
+		t.Fatal("pprof still served after the application closed")
+	}
+}
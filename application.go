@@ -0,0 +1,509 @@
+package tarsgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/glymehrvrd/tafgo/conf"
+)
+
+var ErrInvalidConfig = errors.New("Invalid tars config")
+
+// AdapterConfig is an adapter of the server config, serving one servant on one endpoint.
+type AdapterConfig struct {
+	Name     string
+	Endpoint string
+	Servant  string
+	Protocol string
+	// Threads requests of the servant run at once, unlimited when 0. QueueCap more wait for
+	// at most QueueTimeout, for a free thread when it is 0; requests beyond QueueCap are
+	// rejected with TarsServerOverload.
+	Threads      int
+	QueueCap     int
+	QueueTimeout time.Duration
+	// MaxConns connections are accepted at once on Endpoint, unlimited when 0.
+	MaxConns int
+}
+
+// ServerConfig is the <tars><application><server> section of a TARS server config.
+type ServerConfig struct {
+	App      string
+	Server   string
+	LocalIP  string
+	Local    string
+	BasePath string
+	DataPath string
+	LogPath  string
+	LogSize  int64
+	LogNum   int
+	LogLevel string
+	// Servants of the TARS framework.
+	Log    string
+	Config string
+	Notify string
+	Node   string
+	// Adapters in the order of the config file.
+	Adapters []AdapterConfig
+}
+
+// ClientConfig is the <tars><application><client> section of a TARS server config.
+type ClientConfig struct {
+	Locator                 string
+	SyncInvokeTimeout       time.Duration
+	AsyncInvokeTimeout      time.Duration
+	RefreshEndpointInterval time.Duration
+	ReportInterval          time.Duration
+	Stat                    string
+	Property                string
+	ModuleName              string
+}
+
+// ApplicationConfig is a server config file in the format TARS nodes generate for every
+// server they run, passed to the server with --config.
+type ApplicationConfig struct {
+	// Conf is the whole file, for the sections the application adds to it.
+	Conf *conf.Config
+	// SetDivision "name.area.group" is the set the server belongs to, empty unless
+	// EnableSet. Clients then resolve servants within the set and report it in stat.
+	EnableSet   bool
+	SetDivision string
+	Server      ServerConfig
+	Client      ClientConfig
+}
+
+func millis(c *conf.Config, path string, def int) time.Duration {
+	return time.Duration(c.GetIntDefault(path, def)) * time.Millisecond
+}
+
+// configSize parses sizes such as "10M".
+func configSize(c *conf.Config, path string, def int64) int64 {
+	s := strings.ToUpper(c.GetString(path, ""))
+	unit := int64(1)
+	for suffix, n := range map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30} {
+		if strings.HasSuffix(s, suffix) {
+			s, unit = strings.TrimSuffix(s, suffix), n
+		}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); nil == err {
+		return n * unit
+	}
+	return def
+}
+
+// ParseApplicationConfig parses a TARS server config file.
+func ParseApplicationConfig(data []byte) (*ApplicationConfig, error) {
+	c, err := conf.Parse(data)
+	if nil != err {
+		return nil, fmt.Errorf("%w:%w", ErrInvalidConfig, err)
+	}
+	return NewApplicationConfig(c)
+}
+
+// NewApplicationConfig reads the server config from c.
+func NewApplicationConfig(c *conf.Config) (*ApplicationConfig, error) {
+	if !c.HasDomain("/tars/application") {
+		return nil, fmt.Errorf("%w:missing <tars><application>", ErrInvalidConfig)
+	}
+	enableSet, _ := c.GetBool("/tars/application<enableset>")
+	cfg := &ApplicationConfig{
+		Conf:        c,
+		EnableSet:   enableSet,
+		SetDivision: c.GetString("/tars/application<setdivision>", ""),
+	}
+	if !cfg.EnableSet || cfg.SetDivision == "NULL" {
+		cfg.SetDivision = ""
+	}
+	const client = "/tars/application/client"
+	cfg.Client = ClientConfig{
+		Locator:                 c.GetString(client+"<locator>", ""),
+		SyncInvokeTimeout:       millis(c, client+"<sync-invoke-timeout>", 3000),
+		AsyncInvokeTimeout:      millis(c, client+"<async-invoke-timeout>", 5000),
+		RefreshEndpointInterval: millis(c, client+"<refresh-endpoint-interval>", 60000),
+		ReportInterval:          millis(c, client+"<report-interval>", 60000),
+		Stat:                    c.GetString(client+"<stat>", ""),
+		Property:                c.GetString(client+"<property>", ""),
+		ModuleName:              c.GetString(client+"<modulename>", ""),
+	}
+	const server = "/tars/application/server"
+	if !c.HasDomain(server) {
+		return nil, fmt.Errorf("%w:missing <tars><application><server>", ErrInvalidConfig)
+	}
+	cfg.Server = ServerConfig{
+		App:      c.GetString(server+"<app>", ""),
+		Server:   c.GetString(server+"<server>", ""),
+		LocalIP:  c.GetString(server+"<localip>", ""),
+		Local:    c.GetString(server+"<local>", ""),
+		BasePath: c.GetString(server+"<basepath>", ""),
+		DataPath: c.GetString(server+"<datapath>", ""),
+		LogPath:  c.GetString(server+"<logpath>", ""),
+		LogSize:  configSize(c, server+"<logsize>", 50<<20),
+		LogNum:   c.GetIntDefault(server+"<lognum>", 10),
+		LogLevel: c.GetString(server+"<logLevel>", ""),
+		Log:      c.GetString(server+"<log>", ""),
+		Config:   c.GetString(server+"<config>", ""),
+		Notify:   c.GetString(server+"<notify>", ""),
+		Node:     c.GetString(server+"<node>", ""),
+	}
+	if cfg.Server.App == "" || cfg.Server.Server == "" {
+		return nil, fmt.Errorf("%w:missing app or server", ErrInvalidConfig)
+	}
+	if cfg.Client.ModuleName == "" {
+		cfg.Client.ModuleName = cfg.Server.App + "." + cfg.Server.Server
+	}
+	for _, name := range c.GetDomains(server) {
+		adapter := server + "/" + name
+		a := AdapterConfig{
+			Name:         name,
+			Endpoint:     c.GetString(adapter+"<endpoint>", ""),
+			Servant:      c.GetString(adapter+"<servant>", ""),
+			Protocol:     c.GetString(adapter+"<protocol>", ""),
+			Threads:      c.GetIntDefault(adapter+"<threads>", 0),
+			QueueCap:     c.GetIntDefault(adapter+"<queuecap>", 0),
+			QueueTimeout: millis(c, adapter+"<queuetimeout>", 60000),
+			MaxConns:     c.GetIntDefault(adapter+"<maxconns>", 0),
+		}
+		if a.Endpoint == "" || a.Servant == "" {
+			return nil, fmt.Errorf("%w:adapter %s needs endpoint and servant", ErrInvalidConfig, a.Name)
+		}
+		cfg.Server.Adapters = append(cfg.Server.Adapters, a)
+	}
+	return cfg, nil
+}
+
+func LoadApplicationConfig(path string) (*ApplicationConfig, error) {
+	data, err := os.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	return ParseApplicationConfig(data)
+}
+
+// adapterDispatcher applies the limits of an adapter to the requests of its servant.
+type adapterDispatcher struct {
+	Dispatcher
+	adapter AdapterConfig
+	threads chan struct{}
+	queued  int32
+}
+
+func (d *adapterDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	if nil != d.threads {
+		select {
+		case d.threads <- struct{}{}:
+		default:
+			if d.adapter.QueueCap > 0 && int(atomic.AddInt32(&d.queued, 1)) > d.adapter.QueueCap {
+				atomic.AddInt32(&d.queued, -1)
+				return NewTarsError(TarsServerOverload, "Queue of "+d.adapter.Name+" is full")
+			}
+			var expired <-chan time.Time
+			if d.adapter.QueueTimeout > 0 {
+				timer := time.NewTimer(d.adapter.QueueTimeout)
+				defer timer.Stop()
+				expired = timer.C
+			}
+			select {
+			case d.threads <- struct{}{}:
+			case <-expired:
+				if d.adapter.QueueCap > 0 {
+					atomic.AddInt32(&d.queued, -1)
+				}
+				return NewTarsError(TarsServerQueueTimeout, "Request timed out in the queue of "+d.adapter.Name)
+			}
+			if d.adapter.QueueCap > 0 {
+				atomic.AddInt32(&d.queued, -1)
+			}
+		}
+		defer func() { <-d.threads }()
+	}
+	return d.Dispatcher.Dispatch(ctx, req, resp)
+}
+
+// limitListener closes connections accepted beyond max.
+type limitListener struct {
+	net.Listener
+	max    int32
+	active int32
+}
+
+type limitConn struct {
+	net.Conn
+	l    *limitListener
+	once sync.Once
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if nil != err {
+			return nil, err
+		}
+		if atomic.AddInt32(&l.active, 1) <= l.max {
+			return &limitConn{Conn: conn, l: l}, nil
+		}
+		atomic.AddInt32(&l.active, -1)
+		logMsg(LogLevelWarn, "Too many connections, closing", Field{"remote", conn.RemoteAddr()}, Field{"maxconns", l.max})
+		conn.Close()
+	}
+}
+
+func (c *limitConn) Close() error {
+	c.once.Do(func() { atomic.AddInt32(&c.l.active, -1) })
+	return c.Conn.Close()
+}
+
+// Application runs a server the way TARS nodes expect, configured by its server config:
+// it serves the servants added with AddServant on their adapters, resolves servants
+// through the locator, reports stat and logs to the configured servants.
+type Application struct {
+	Config *ApplicationConfig
+	Server *Server
+	// Stat and Property report to the stat and property servants of the config, nil if
+	// there are none.
+	Stat     *StatReporter
+	Property *PropertyReporter
+	// RemoteConfig downloads the files of the config servant, nil if there is none.
+	RemoteConfig *RemoteConfig
+	// Admin serves the commands of TARS nodes on the local endpoint.
+	Admin *Admin
+
+	adminServer *Server
+	mutex       sync.Mutex
+	servants    map[string]Dispatcher
+	loggers     map[string]Logger
+	listenAddrs map[string]string
+	closers     []func() error
+	errs        chan error
+}
+
+func NewApplication(cfg *ApplicationConfig) *Application {
+	a := &Application{
+		Config:      cfg,
+		Server:      NewServer(),
+		adminServer: NewServer(),
+		servants:    make(map[string]Dispatcher),
+		loggers:     make(map[string]Logger),
+		listenAddrs: make(map[string]string),
+		errs:        make(chan error, len(cfg.Server.Adapters)+1),
+	}
+	a.Admin = newAdmin(a)
+	return a
+}
+
+// LoadApplication creates the application of the config file at path.
+func LoadApplication(path string) (*Application, error) {
+	cfg, err := LoadApplicationConfig(path)
+	if nil != err {
+		return nil, err
+	}
+	return NewApplication(cfg), nil
+}
+
+// AddServant serves d on the adapter whose servant is servant. It must be called before
+// Start.
+func (a *Application) AddServant(servant string, d Dispatcher) {
+	a.mutex.Lock()
+	a.servants[servant] = d
+	a.mutex.Unlock()
+}
+
+// listenAddr returns the address the adapter called name listens on, empty if it has no
+// stream listener.
+func (a *Application) listenAddr(name string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.listenAddrs[name]
+}
+
+// Name returns "App.Server".
+func (a *Application) Name() string {
+	return a.Config.Server.App + "." + a.Config.Server.Server
+}
+
+// ClientOptions returns the options clients of the application should be created with, so
+// that their calls are reported to the stat servant.
+func (a *Application) ClientOptions() []ClientOption {
+	if nil == a.Stat {
+		return nil
+	}
+	return []ClientOption{WithMetrics(a.Stat)}
+}
+
+// Logger returns the logger of the named business log, like FDLOG of the other TARS
+// languages: lines go to the log servant if the config has one, to a log per day in
+// LogPath otherwise.
+func (a *Application) Logger(file string) Logger {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if l, exist := a.loggers[file]; exist {
+		return l
+	}
+	var l Logger
+	if cfg := a.Config.Server; cfg.Log != "" {
+		r := NewRemoteLogger(cfg.Log, RemoteLogConfig{App: cfg.App, Server: cfg.Server, File: file, SetDivision: a.Config.SetDivision})
+		a.closers = append(a.closers, r.Close)
+		l = NewWriterLogger(r)
+	} else {
+		name := a.Name()
+		if file != "" {
+			name += "_" + file
+		}
+		w := NewDayWriter(filepath.Join(cfg.LogPath, cfg.App, cfg.Server, name))
+		a.closers = append(a.closers, w.Close)
+		l = NewWriterLogger(w)
+	}
+	a.loggers[file] = l
+	return l
+}
+
+// Start sets up logging, naming and reporting from the config and serves the servants on
+// their adapters in the background. When it fails, whatever it set up is closed again.
+func (a *Application) Start() error {
+	if err := a.start(); nil != err {
+		a.Close()
+		return err
+	}
+	return nil
+}
+
+func (a *Application) start() error {
+	cfg := a.Config
+	if cfg.Server.LogLevel != "" {
+		level, err := ParseLogLevel(cfg.Server.LogLevel)
+		if nil != err {
+			return err
+		}
+		SetLogLevel(level)
+	}
+	if cfg.Server.LogPath != "" {
+		w, err := NewRollWriter(filepath.Join(cfg.Server.LogPath, cfg.Server.App, cfg.Server.Server, a.Name()+".log"), cfg.Server.LogSize, cfg.Server.LogNum)
+		if nil != err {
+			return err
+		}
+		SetLogger(NewWriterLogger(w))
+		a.closers = append(a.closers, func() error {
+			SetLogger(nil)
+			return w.Close()
+		})
+	}
+	if cfg.Client.Locator != "" {
+		naming := newNamingProxy(cfg.Client.Locator, cfg.Client.SyncInvokeTimeout)
+		resolver := NewRegistryResolver(naming)
+		resolver.RefreshInterval = cfg.Client.RefreshEndpointInterval
+		resolver.SetId = cfg.SetDivision
+		prevResolver, prevNaming := DefaultResolver, DefaultNamingService
+		DefaultResolver, DefaultNamingService = resolver, naming
+		a.closers = append(a.closers, func() error {
+			DefaultResolver, DefaultNamingService = prevResolver, prevNaming
+			return naming.TarsClient.Close()
+		})
+	}
+	statConfig := StatConfig{
+		Module:      cfg.Client.ModuleName,
+		MasterIp:    cfg.Server.LocalIP,
+		SetDivision: cfg.SetDivision,
+		Interval:    cfg.Client.ReportInterval,
+	}
+	if cfg.Client.Stat != "" {
+		a.Stat = NewStatReporter(cfg.Client.Stat, statConfig)
+		a.closers = append(a.closers, a.Stat.Close)
+	}
+	if cfg.Client.Property != "" {
+		a.Property = NewPropertyReporter(cfg.Client.Property, statConfig)
+		a.closers = append(a.closers, a.Property.Close)
+	}
+	if cfg.Server.Config != "" {
+		a.RemoteConfig = NewRemoteConfig(cfg.Server.Config, RemoteConfigOptions{App: cfg.Server.App, Server: cfg.Server.Server, SetDivision: cfg.SetDivision, Dir: cfg.Server.BasePath})
+		a.closers = append(a.closers, a.RemoteConfig.Close)
+	}
+	if cfg.Server.Notify != "" {
+		a.Admin.setNotify(cfg.Server.Notify)
+	}
+	if cfg.Server.Local != "" {
+		// nodes reach AdminObj on the local endpoint only, not on the business adapters
+		a.adminServer.AddServant(AdminServant, &AdminFDispatcher{a.Admin})
+		l, _, err := a.adminServer.listen(cfg.Server.Local)
+		if nil != err {
+			return fmt.Errorf("Failed to listen on local endpoint:%w", err)
+		}
+		logMsg(LogLevelInfo, "Serving admin", Field{FieldEndpoint, cfg.Server.Local})
+		go func() { a.errs <- a.adminServer.Serve(l) }()
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, adapter := range cfg.Server.Adapters {
+		d, exist := a.servants[adapter.Servant]
+		if !exist {
+			logMsg(LogLevelWarn, "No servant for adapter", Field{"adapter", adapter.Name}, Field{FieldServant, adapter.Servant})
+			continue
+		}
+		if adapter.Threads > 0 {
+			d = &adapterDispatcher{Dispatcher: d, adapter: adapter, threads: make(chan struct{}, adapter.Threads)}
+		}
+		a.Server.AddServant(adapter.Servant, d)
+		l, pc, err := a.Server.listen(adapter.Endpoint)
+		if nil != err {
+			return fmt.Errorf("Failed to listen on adapter %s:%w", adapter.Name, err)
+		}
+		logMsg(LogLevelInfo, "Serving adapter", Field{"adapter", adapter.Name}, Field{FieldServant, adapter.Servant}, Field{FieldEndpoint, adapter.Endpoint})
+		if nil != pc {
+			go func() { a.errs <- a.Server.ServePacket(pc) }()
+			continue
+		}
+		a.listenAddrs[adapter.Name] = l.Addr().String()
+		if adapter.MaxConns > 0 {
+			l = &limitListener{Listener: l, max: int32(adapter.MaxConns)}
+		}
+		go func() { a.errs <- a.Server.Serve(l) }()
+	}
+	return nil
+}
+
+// Run starts the application and serves until SIGINT or SIGTERM, until a node shuts it
+// down through AdminObj or until an adapter fails, then closes it.
+func (a *Application) Run() error {
+	if err := a.Start(); nil != err {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	var err error
+	select {
+	case sig := <-signals:
+		logMsg(LogLevelInfo, "Stopping on signal", Field{"signal", sig})
+	case <-a.Admin.stop:
+	case err = <-a.errs:
+	}
+	a.Close()
+	if err == ErrServerClosed {
+		err = nil
+	}
+	return err
+}
+
+// Close stops serving, then flushes and closes the reporters and loggers.
+func (a *Application) Close() error {
+	a.Server.Close()
+	a.adminServer.Close()
+	a.Admin.close()
+	a.mutex.Lock()
+	closers := a.closers
+	a.closers = nil
+	a.mutex.Unlock()
+	for i := len(closers) - 1; i >= 0; i-- {
+		closers[i]()
+	}
+	return nil
+}
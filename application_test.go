@@ -0,0 +1,288 @@
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testApplicationConfig = `<tars>
+  <application>
+    enableset=y
+    setdivision=test.sz.1
+    <client>
+      locator=%s
+      sync-invoke-timeout=1000
+      refresh-endpoint-interval=30000
+      stat=%s
+      report-interval=60000
+    </client>
+    <server>
+      app=Test
+      server=EchoServer
+      localip=127.0.0.1
+      logpath=%s
+      logsize=1M
+      lognum=3
+      logLevel=INFO
+      # adapters
+      <Test.EchoServer.EchoObjAdapter>
+        allow
+        endpoint=tcp -h 127.0.0.1 -p %d -t 60000
+        servant=Test.EchoServer.EchoObj
+        threads=4
+        maxconns=1
+        queuecap=100
+        protocol=tars
+      </Test.EchoServer.EchoObjAdapter>
+      <Test.EchoServer.SlowObjAdapter>
+        endpoint=tcp -h 127.0.0.1 -p %d
+        servant=Test.EchoServer.SlowObj
+        threads=1
+        queuecap=1
+        queuetimeout=100
+      </Test.EchoServer.SlowObjAdapter>
+    </server>
+  </application>
+</tars>
+`
+
+// freePort returns a local TCP port nobody listens on.
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestApplicationConfig(t *testing.T) {
+	echoPort, slowPort := freePort(t), freePort(t)
+	data := fmt.Sprintf(testApplicationConfig, "tars.tarsregistry.QueryObj@tcp -h 127.0.0.1 -p 17890", "tars.tarsstat.StatObj", "/tmp/logs", echoPort, slowPort)
+	cfg, err := ParseApplicationConfig([]byte(data))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !cfg.EnableSet || cfg.SetDivision != "test.sz.1" || cfg.Client.SyncInvokeTimeout != time.Second || cfg.Client.ModuleName != "Test.EchoServer" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if s := cfg.Server; s.App != "Test" || s.LogSize != 1<<20 || s.LogNum != 3 || s.LogLevel != "INFO" || len(s.Adapters) != 2 {
+		t.Fatalf("unexpected server config %+v", s)
+	}
+	expected := AdapterConfig{
+		Name:         "Test.EchoServer.SlowObjAdapter",
+		Endpoint:     fmt.Sprintf("tcp -h 127.0.0.1 -p %d", slowPort),
+		Servant:      "Test.EchoServer.SlowObj",
+		Threads:      1,
+		QueueCap:     1,
+		QueueTimeout: 100 * time.Millisecond,
+	}
+	if a := cfg.Server.Adapters[1]; a != expected {
+		t.Fatalf("unexpected adapter %+v", a)
+	}
+	if cfg.Client.RefreshEndpointInterval != 30*time.Second {
+		t.Fatalf("unexpected refresh interval %v", cfg.Client.RefreshEndpointInterval)
+	}
+	cfg, err = ParseApplicationConfig([]byte(strings.Replace(data, "enableset=y", "enableset=n", 1)))
+	if nil != err || cfg.EnableSet || cfg.SetDivision != "" {
+		t.Fatalf("set division applied without enableset: %+v, %v", cfg, err)
+	}
+	if lines := cfg.Conf.GetDomainLines("/tars/application/server/Test.EchoServer.EchoObjAdapter"); len(lines) != 1 || lines[0] != "allow" {
+		t.Fatalf("unexpected adapter lines %q", lines)
+	}
+
+	for _, invalid := range []string{
+		"<tars>\n<application>\n</tars>\n",
+		"<tars>\n<application>\n<server>\n</server>\n</application>\n</tars>\n",
+		"<tars>\n<application>\n<server>\napp=a\nserver=b\n<adapter>\n</adapter>\n</server>\n</application>\n</tars>\n",
+	} {
+		if _, err := ParseApplicationConfig([]byte(invalid)); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("parsed %q:%v", invalid, err)
+		}
+	}
+}
+
+func TestApplication(t *testing.T) {
+	defer func(resolver Resolver, naming *QueryFProxy) {
+		DefaultResolver, DefaultNamingService = resolver, naming
+	}(DefaultResolver, DefaultNamingService)
+	defer SetLogLevel(GetLogLevel())
+
+	echoPort, slowPort := freePort(t), freePort(t)
+	r, err := NewRegistry(&RegistryConfig{Objects: map[string][]RegistryEndpoint{
+		"Test.EchoServer.EchoObj": {
+			{Endpoint: fmt.Sprintf("tcp -h 127.0.0.1 -p %d", echoPort), SetId: "test.sz.1"},
+			// only the endpoints of the set of the application are used
+			{Endpoint: fmt.Sprintf("tcp -h 127.0.0.1 -p %d", freePort(t)), SetId: "test.sh.1"},
+		},
+	}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	_, locator := startTestServer(t, "tars.tarsregistry.QueryObj", r)
+	recorder := &statRecorder{msgs: make(map[StatMicMsgHead]StatMicMsgBody)}
+	_, statObj := startTestServer(t, "tars.tarsstat.StatObj", &StatFDispatcher{recorder})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Test.EchoServer.config.conf")
+	data := fmt.Sprintf(testApplicationConfig, locator, statObj, dir, echoPort, slowPort)
+	if err := os.WriteFile(path, []byte(data), 0644); nil != err {
+		t.Fatal(err)
+	}
+
+	resolver := DefaultResolver
+	app, err := LoadApplication(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	app.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	app.AddServant("Test.EchoServer.SlowObj", slowDispatcher(300*time.Millisecond))
+	if err := app.Start(); nil != err {
+		t.Fatal(err)
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			app.Close()
+		}
+	}()
+
+	// resolved through the locator
+	if r, ok := DefaultResolver.(*RegistryResolver); !ok || r.RefreshInterval != 30*time.Second || r.SetId != "test.sz.1" {
+		t.Fatalf("unexpected resolver %+v", DefaultResolver)
+	}
+	c := NewClient("Test.EchoServer.EchoObj", time.Second, app.ClientOptions()...)
+	defer c.Close()
+	if resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err || string(resp.SBuffer) != "hello" {
+		t.Fatalf("echo returned %v, %v", resp, err)
+	}
+	// maxconns=1 closes a second connection
+	extra, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", echoPort))
+	if nil != err {
+		t.Fatal(err)
+	}
+	extra.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := extra.Read(make([]byte, 1)); nil == err {
+		t.Fatal("connection beyond maxconns was served")
+	}
+	extra.Close()
+
+	// threads=1, queuecap=1: one call runs, one waits until the queue timeout and one is
+	// rejected
+	slow := NewClient(fmt.Sprintf("Test.EchoServer.SlowObj@tcp -h 127.0.0.1 -p %d", slowPort), time.Second)
+	defer slow.Close()
+	codes := make([]int32, 3)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := slow.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+			codes[i] = ErrorCode(err)
+		}(i)
+		time.Sleep(30 * time.Millisecond)
+	}
+	wg.Wait()
+	if codes[0] != TarsServerSuccess || codes[1] != TarsServerQueueTimeout || codes[2] != TarsServerOverload {
+		t.Fatalf("unexpected codes %v", codes)
+	}
+
+	app.Logger("access").Log(LogLevelInfo, "served")
+	app.Close()
+	closed = true
+	if DefaultResolver != resolver {
+		t.Fatal("locator resolver still in use after Close")
+	}
+	recorder.mutex.Lock()
+	var reported []StatMicMsgHead
+	for head := range recorder.msgs {
+		reported = append(reported, head)
+	}
+	recorder.mutex.Unlock()
+	if len(reported) != 1 || reported[0].MasterName != "Test.EchoServer.testsz1" {
+		t.Fatalf("unexpected stat %+v", recorder.msgs)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "Test", "EchoServer", "Test.EchoServer.log"))
+	if nil != err || !strings.Contains(string(b), "|INFO|Serving adapter") {
+		t.Fatalf("unexpected server log %q, %v", b, err)
+	}
+	day := time.Now().Format("20060102")
+	b, err = os.ReadFile(filepath.Join(dir, "Test", "EchoServer", "Test.EchoServer_access_"+day+".log"))
+	if nil != err || !strings.HasSuffix(string(b), "|INFO|served\n") {
+		t.Fatalf("unexpected access log %q, %v", b, err)
+	}
+}
+
+func TestApplicationStartFailure(t *testing.T) {
+	defer SetLogLevel(GetLogLevel())
+	recorder := &statRecorder{msgs: make(map[StatMicMsgHead]StatMicMsgBody)}
+	_, statObj := startTestServer(t, "tars.tarsstat.StatObj", &StatFDispatcher{recorder})
+	// the stat servant is resolved through the locator
+	_, statEndpoints, _ := ParseProxy(statObj)
+	r, err := NewRegistry(&RegistryConfig{Objects: map[string][]RegistryEndpoint{
+		"tars.tarsstat.StatObj": {{Endpoint: statEndpoints[0].String(), SetId: "test.sz.1"}},
+	}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	_, locator := startTestServer(t, "tars.tarsregistry.QueryObj", r)
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	dir := t.TempDir()
+	data := fmt.Sprintf(testApplicationConfig, locator, "tars.tarsstat.StatObj", dir, freePort(t), taken.Addr().(*net.TCPAddr).Port)
+	cfg, err := ParseApplicationConfig([]byte(data))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	resolver, naming := DefaultResolver, DefaultNamingService
+	before := runtime.NumGoroutine()
+	app := NewApplication(cfg)
+	app.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	app.AddServant("Test.EchoServer.SlowObj", echoDispatcher{})
+	if err = app.Start(); nil == err {
+		app.Close()
+		t.Fatal("started on a port in use")
+	}
+	if _, ok := currentLogger.Load().(loggerHolder).Logger.(stdLogger); !ok {
+		t.Fatal("logger was not reset after the failed start")
+	}
+	if DefaultResolver != resolver || DefaultNamingService != naming {
+		t.Fatal("locator was not reset after the failed start")
+	}
+	checkGoroutines(t, before)
+}
+
+func TestAdapterQueueWithoutTimeout(t *testing.T) {
+	// queuetimeout=0 waits for a free thread instead of timing out right away
+	d := &adapterDispatcher{
+		Dispatcher: slowDispatcher(50 * time.Millisecond),
+		adapter:    AdapterConfig{Name: "Test.EchoServer.SlowObjAdapter", Threads: 1, QueueCap: 1},
+		threads:    make(chan struct{}, 1),
+	}
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = d.Dispatch(context.Background(), &RequestPacket{SFuncName: "echo"}, &ResponsePacket{})
+		}(i)
+	}
+	wg.Wait()
+	if nil != errs[0] || nil != errs[1] {
+		t.Fatalf("queued request failed: %v", errs)
+	}
+}
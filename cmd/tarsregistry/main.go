@@ -0,0 +1,54 @@
+// Command tarsregistry serves a QueryF registry backed by a JSON config file, so that
+// clients using NewDefaultNaming can resolve servants without a full TARS deployment.
+//
+// Send SIGHUP to reload the config file.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	tarsgo "github.com/glymehrvrd/tafgo"
+)
+
+func main() {
+	config := flag.String("config", "registry.json", "registry config file")
+	endpoint := flag.String("endpoint", "tcp -h 127.0.0.1 -p 17890", "endpoint to listen on")
+	obj := flag.String("obj", "tars.tarsregistry.QueryObj", "servant name of the registry")
+	flag.Parse()
+
+	registry, err := tarsgo.LoadRegistry(*config)
+	if nil != err {
+		log.Fatalf("Failed to load registry config:%v", err)
+	}
+	server := tarsgo.NewServer()
+	server.AddServant(*obj, registry)
+	l, err := tarsgo.Listen(*endpoint)
+	if nil != err {
+		log.Fatalf("Failed to listen on %s:%v", *endpoint, err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			if sig != syscall.SIGHUP {
+				server.Close()
+				return
+			}
+			if err := registry.Reload(); nil != err {
+				log.Printf("Failed to reload registry config:%v", err)
+			} else {
+				log.Printf("Registry config %s reloaded", *config)
+			}
+		}
+	}()
+
+	log.Printf("Serving %s@%s", *obj, *endpoint)
+	if err := server.Serve(l); nil != err && err != tarsgo.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"log"
 	"reflect"
 )
 
@@ -186,9 +185,15 @@ func encodeValueWithTag(buf *bytes.Buffer, tag uint8, v *reflect.Value) error {
 		return encodeValueWithTag(buf, tag, &rv)
 	case reflect.Struct:
 		encodeHeaderTag(tag, uint8(TarsHeadeStructBegin), buf)
-		ts, ok := v.Interface().(TarsEncoder)
+		sv := *v
+		if !sv.CanAddr() {
+			// map keys and values are not addressable, encode a copy
+			sv = reflect.New(v.Type()).Elem()
+			sv.Set(*v)
+		}
+		ts, ok := sv.Addr().Interface().(TarsEncoder)
 		if !ok {
-			log.Printf("Invalid type:%v", v.Type())
+			logMsg(LogLevelError, "Invalid type", Field{"type", v.Type()})
 		} else {
 			ts.Encode(buf)
 		}
@@ -412,7 +417,7 @@ func decodeTagIntegerValue(buf *bytes.Buffer, tag uint8, required bool, typeValu
 			if buf.Len() < 1 {
 				return 0, ErrBufferPeekOverflow
 			}
-			return int64(buf.Next(1)[0]), nil
+			return int64(int8(buf.Next(1)[0])), nil
 		case TarsHeadeShort:
 			if buf.Len() < 2 {
 				return 0, ErrBufferPeekOverflow
@@ -28,3 +28,31 @@ func TestCodec(t *testing.T) {
 	}
 	t.Logf("####%v", v2)
 }
+
+func TestCodecNegativeChar(t *testing.T) {
+	// small integers are sent as a signed char
+	var buf bytes.Buffer
+	EncodeTagInt32Value(&buf, -5, 1)
+	EncodeTagInt64Value(&buf, -128, 2)
+	var i32 int32
+	var i64 int64
+	if err := DecodeTagInt32Value(&buf, &i32, 1, true); nil != err || i32 != -5 {
+		t.Fatalf("decoded %d, %v", i32, err)
+	}
+	if err := DecodeTagInt64Value(&buf, &i64, 2, true); nil != err || i64 != -128 {
+		t.Fatalf("decoded %d, %v", i64, err)
+	}
+}
+
+func TestCodecStructMap(t *testing.T) {
+	// map values are not addressable, they are encoded from a copy
+	var buf bytes.Buffer
+	in := map[string]EndpointF{"a": {Host: "10.0.0.1", Port: 10001, SetId: "app.sz.1"}}
+	if err := EncodeTagMapValue(&buf, in, 1); nil != err {
+		t.Fatal(err)
+	}
+	var out map[string]EndpointF
+	if err := DecodeTagMapValue(&buf, &out, 1, true); nil != err || out["a"] != in["a"] {
+		t.Fatalf("decoded %v, %v", out, err)
+	}
+}
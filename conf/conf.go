@@ -0,0 +1,362 @@
+// Package conf reads and writes config files in the TARS format: nested <domain> sections
+// holding key=value lines and plain lines, with # starting a comment line.
+//
+//	<tars>
+//	  <application>
+//	    <client>
+//	      sync-invoke-timeout=3000
+//	    </client>
+//	  </application>
+//	</tars>
+//
+// Values are addressed by paths naming the domains and the key, such as
+// "/tars/application/client<sync-invoke-timeout>".
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidFormat = errors.New("Invalid tars config format")
+	ErrInvalidPath   = errors.New("Invalid tars config path")
+	ErrNotFound      = errors.New("Tars config value not found")
+)
+
+// Domain is a <section> of a config file.
+type Domain struct {
+	name    string
+	keys    []string
+	params  map[string]string
+	lines   []string
+	domains []*Domain
+}
+
+func newDomain(name string) *Domain {
+	return &Domain{name: name, params: make(map[string]string)}
+}
+
+func (d *Domain) Name() string {
+	return d.name
+}
+
+func (d *Domain) child(name string) *Domain {
+	for _, c := range d.domains {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// addChild returns the child called name, adding it if there is none, so that sections
+// appearing twice are merged.
+func (d *Domain) addChild(name string) *Domain {
+	if c := d.child(name); nil != c {
+		return c
+	}
+	c := newDomain(name)
+	d.domains = append(d.domains, c)
+	return c
+}
+
+func (d *Domain) set(key, value string) {
+	if _, exist := d.params[key]; !exist {
+		d.keys = append(d.keys, key)
+	}
+	d.params[key] = value
+}
+
+// Config is a parsed config file. It is not safe for concurrent modification.
+type Config struct {
+	root *Domain
+}
+
+func New() *Config {
+	return &Config{root: newDomain("")}
+}
+
+// Parse reads a config file. Lines without "=" inside a domain are kept as its lines,
+// such as the "allow" entries of adapters.
+func Parse(data []byte) (*Config, error) {
+	c := New()
+	stack := []*Domain{c.root}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		top := stack[len(stack)-1]
+		switch {
+		case strings.HasPrefix(line, "</") && strings.HasSuffix(line, ">"):
+			name := strings.TrimSpace(line[2 : len(line)-1])
+			if len(stack) == 1 || name != top.name {
+				return nil, fmt.Errorf("%w:unexpected </%s> on line %d", ErrInvalidFormat, name, lineno)
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">"):
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" || strings.ContainsAny(name, "<>/") {
+				return nil, fmt.Errorf("%w:invalid domain %s on line %d", ErrInvalidFormat, line, lineno)
+			}
+			stack = append(stack, top.addChild(name))
+		case len(stack) == 1:
+			return nil, fmt.Errorf("%w:line %d is outside of any domain", ErrInvalidFormat, lineno)
+		default:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				top.set(strings.TrimSpace(key), strings.TrimSpace(value))
+			} else {
+				top.lines = append(top.lines, line)
+			}
+		}
+	}
+	if err := scanner.Err(); nil != err {
+		return nil, err
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("%w:<%s> is not closed", ErrInvalidFormat, stack[len(stack)-1].name)
+	}
+	return c, nil
+}
+
+func ParseFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// splitPath splits "/a/b<key>" into its domains and key. The key is empty for a path
+// naming a domain, such as "/a/b".
+func splitPath(path string) ([]string, string, error) {
+	key := ""
+	if i := strings.IndexByte(path, '<'); i >= 0 {
+		if !strings.HasSuffix(path, ">") {
+			return nil, "", fmt.Errorf("%w:%s", ErrInvalidPath, path)
+		}
+		path, key = path[:i], path[i+1:len(path)-1]
+		if key == "" {
+			return nil, "", fmt.Errorf("%w:%s", ErrInvalidPath, path)
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, "", fmt.Errorf("%w:%s", ErrInvalidPath, path)
+	}
+	var names []string
+	for _, name := range strings.Split(path[1:], "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, key, nil
+}
+
+func (c *Config) domain(names []string) *Domain {
+	d := c.root
+	for _, name := range names {
+		if d = d.child(name); nil == d {
+			return nil
+		}
+	}
+	return d
+}
+
+// Domain returns the domain at path, such as "/tars/application", or nil.
+func (c *Config) Domain(path string) *Domain {
+	names, key, err := splitPath(path)
+	if nil != err || key != "" {
+		return nil
+	}
+	return c.domain(names)
+}
+
+func (c *Config) HasDomain(path string) bool {
+	return nil != c.Domain(path)
+}
+
+// Get returns the value at path, such as "/tars/application/server<app>".
+func (c *Config) Get(path string) (string, error) {
+	names, key, err := splitPath(path)
+	if nil != err {
+		return "", err
+	}
+	if key == "" {
+		return "", fmt.Errorf("%w:%s names no key", ErrInvalidPath, path)
+	}
+	if d := c.domain(names); nil != d {
+		if value, exist := d.params[key]; exist {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("%w:%s", ErrNotFound, path)
+}
+
+// GetString returns the value at path, or def if there is none.
+func (c *Config) GetString(path, def string) string {
+	if value, err := c.Get(path); nil == err {
+		return value
+	}
+	return def
+}
+
+func (c *Config) GetInt(path string) (int, error) {
+	value, err := c.Get(path)
+	if nil != err {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// GetIntDefault returns the integer at path, or def if there is none or it is not an
+// integer.
+func (c *Config) GetIntDefault(path string, def int) int {
+	if n, err := c.GetInt(path); nil == err {
+		return n
+	}
+	return def
+}
+
+func (c *Config) GetInt64(path string) (int64, error) {
+	value, err := c.Get(path)
+	if nil != err {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func (c *Config) GetFloat64(path string) (float64, error) {
+	value, err := c.Get(path)
+	if nil != err {
+		return 0, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// GetBool parses the TARS flags "y" and "n" as well as the values strconv.ParseBool
+// accepts.
+func (c *Config) GetBool(path string) (bool, error) {
+	value, err := c.Get(path)
+	if nil != err {
+		return false, err
+	}
+	switch strings.ToLower(value) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// GetDomains returns the names of the domains in the domain at path, in file order.
+func (c *Config) GetDomains(path string) []string {
+	d := c.Domain(path)
+	if nil == d {
+		return nil
+	}
+	names := make([]string, len(d.domains))
+	for i, child := range d.domains {
+		names[i] = child.name
+	}
+	return names
+}
+
+// GetDomainMap returns the key=value lines of the domain at path.
+func (c *Config) GetDomainMap(path string) map[string]string {
+	d := c.Domain(path)
+	if nil == d {
+		return nil
+	}
+	m := make(map[string]string, len(d.params))
+	for k, v := range d.params {
+		m[k] = v
+	}
+	return m
+}
+
+// GetDomainLines returns the lines without "=" of the domain at path, in file order.
+func (c *Config) GetDomainLines(path string) []string {
+	d := c.Domain(path)
+	if nil == d {
+		return nil
+	}
+	return append([]string(nil), d.lines...)
+}
+
+// Set sets the value at path, adding the domains that do not exist.
+func (c *Config) Set(path, value string) error {
+	names, key, err := splitPath(path)
+	if nil != err {
+		return err
+	}
+	if key == "" || len(names) == 0 || strings.ContainsAny(key, "=\n") || strings.Contains(value, "\n") {
+		return fmt.Errorf("%w:%s", ErrInvalidPath, path)
+	}
+	d := c.root
+	for _, name := range names {
+		d = d.addChild(name)
+	}
+	d.set(key, value)
+	return nil
+}
+
+// AddLine appends a line without "=" to the domain at path, adding the domains that do not
+// exist.
+func (c *Config) AddLine(path, line string) error {
+	names, key, err := splitPath(path)
+	if nil != err {
+		return err
+	}
+	line = strings.TrimSpace(line)
+	if key != "" || len(names) == 0 || line == "" || strings.ContainsAny(line, "=\n") || strings.HasPrefix(line, "<") || strings.HasPrefix(line, "#") {
+		return fmt.Errorf("%w:%s", ErrInvalidPath, path)
+	}
+	d := c.root
+	for _, name := range names {
+		d = d.addChild(name)
+	}
+	d.lines = append(d.lines, line)
+	return nil
+}
+
+func writeDomain(b *strings.Builder, d *Domain, indent string) {
+	b.WriteString(indent + "<" + d.name + ">\n")
+	for _, key := range d.keys {
+		b.WriteString(indent + "  " + key + "=" + d.params[key] + "\n")
+	}
+	for _, line := range d.lines {
+		b.WriteString(indent + "  " + line + "\n")
+	}
+	for _, child := range d.domains {
+		writeDomain(b, child, indent+"  ")
+	}
+	b.WriteString(indent + "</" + d.name + ">\n")
+}
+
+// String formats c as a config file, indenting nested domains by two spaces. Comments of
+// a parsed file are not kept.
+func (c *Config) String() string {
+	var b strings.Builder
+	for _, d := range c.root.domains {
+		writeDomain(&b, d, "")
+	}
+	return b.String()
+}
+
+func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, c.String())
+	return int64(n), err
+}
+
+func (c *Config) WriteFile(path string) error {
+	return os.WriteFile(path, []byte(c.String()), 0644)
+}
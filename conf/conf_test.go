@@ -0,0 +1,136 @@
+package conf
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testConfig = `# generated by tarsnode
+<tars>
+  <application>
+    enableset=n
+    <client>
+      locator=tars.tarsregistry.QueryObj@tcp -h 127.0.0.1 -p 17890:tcp -h 127.0.0.2 -p 17890
+      sync-invoke-timeout = 3000
+      sample-rate=0.5
+    </client>
+    <server>
+      app=Test
+      <Test.HelloServer.HelloObjAdapter>
+        allow
+        deny 10.0.0.0/8
+        endpoint=tcp -h 127.0.0.1 -p 10015 -t 60000
+      </Test.HelloServer.HelloObjAdapter>
+    </server>
+  </application>
+</tars>
+<tars>
+  <application>
+    <server>
+      server=HelloServer
+    </server>
+  </application>
+</tars>
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(testConfig))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if v, err := c.Get("/tars/application/client<locator>"); nil != err || v != "tars.tarsregistry.QueryObj@tcp -h 127.0.0.1 -p 17890:tcp -h 127.0.0.2 -p 17890" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	if n, err := c.GetInt("/tars/application/client<sync-invoke-timeout>"); nil != err || n != 3000 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if f, err := c.GetFloat64("/tars/application/client<sample-rate>"); nil != err || f != 0.5 {
+		t.Fatalf("got %v, %v", f, err)
+	}
+	if b, err := c.GetBool("/tars/application<enableset>"); nil != err || b {
+		t.Fatalf("got %v, %v", b, err)
+	}
+	if _, err := c.Get("/tars/application/client<missing>"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := c.GetInt("/tars/application/server<app>"); nil == err {
+		t.Fatal("parsed app as an integer")
+	}
+	if n := c.GetIntDefault("/tars/application/client<async-invoke-timeout>", 5000); n != 5000 {
+		t.Fatalf("unexpected default %d", n)
+	}
+	if s := c.GetString("/tars/application/server<server>", ""); s != "HelloServer" {
+		t.Fatalf("merged domain lost %q", s)
+	}
+	if domains := c.GetDomains("/tars/application/server"); !reflect.DeepEqual(domains, []string{"Test.HelloServer.HelloObjAdapter"}) {
+		t.Fatalf("unexpected domains %v", domains)
+	}
+	adapter := "/tars/application/server/Test.HelloServer.HelloObjAdapter"
+	if lines := c.GetDomainLines(adapter); !reflect.DeepEqual(lines, []string{"allow", "deny 10.0.0.0/8"}) {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+	if m := c.GetDomainMap(adapter); len(m) != 1 || m["endpoint"] != "tcp -h 127.0.0.1 -p 10015 -t 60000" {
+		t.Fatalf("unexpected map %v", m)
+	}
+	if !c.HasDomain("/tars/application/") || c.HasDomain("/tars/missing") || nil != c.Domain("/tars<app>") {
+		t.Fatal("unexpected domains")
+	}
+	for _, path := range []string{"tars/application<enableset>", "/tars/application<enableset", "/tars/application<>", "/tars/application"} {
+		if _, err := c.Get(path); !errors.Is(err, ErrInvalidPath) {
+			t.Fatalf("%s: unexpected error %v", path, err)
+		}
+	}
+
+	for _, invalid := range []string{
+		"<tars>\n",
+		"<tars>\n</application>\n",
+		"</tars>\n",
+		"key=value\n",
+		"<>\n</>\n",
+	} {
+		if _, err := Parse([]byte(invalid)); !errors.Is(err, ErrInvalidFormat) {
+			t.Fatalf("parsed %q:%v", invalid, err)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	c := New()
+	for _, kv := range [][2]string{
+		{"/tars/application/server<app>", "Test"},
+		{"/tars/application/server<server>", "HelloServer"},
+		{"/tars/application/server<app>", "Demo"},
+	} {
+		if err := c.Set(kv[0], kv[1]); nil != err {
+			t.Fatal(err)
+		}
+	}
+	if err := c.AddLine("/tars/application/server/Demo.HelloServer.HelloObjAdapter", "allow"); nil != err {
+		t.Fatal(err)
+	}
+	for _, err := range []error{c.Set("/<key>", "v"), c.Set("/tars<a=b>", "v"), c.Set("/tars<key>", "a\nb"), c.AddLine("/tars", "k=v"), c.AddLine("/tars<key>", "allow")} {
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	text := c.String()
+	parsed, err := Parse([]byte(text))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if parsed.String() != text {
+		t.Fatalf("config changed by a round trip:\n%s\n%s", text, parsed.String())
+	}
+	if app := parsed.GetString("/tars/application/server<app>", ""); app != "Demo" {
+		t.Fatalf("unexpected app %q", app)
+	}
+	if lines := parsed.GetDomainLines("/tars/application/server/Demo.HelloServer.HelloObjAdapter"); !reflect.DeepEqual(lines, []string{"allow"}) {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+	expected := "<tars>\n  <application>\n    <server>\n      app=Demo\n      server=HelloServer\n      <Demo.HelloServer.HelloObjAdapter>\n        allow\n      </Demo.HelloServer.HelloObjAdapter>\n    </server>\n  </application>\n</tars>\n"
+	if text != expected {
+		t.Fatalf("unexpected config:\n%s", text)
+	}
+}
@@ -0,0 +1,145 @@
+package tarsgo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidConfigName = errors.New("Invalid config file name")
+	ErrLoadConfig        = errors.New("Tars config load failed")
+)
+
+// RemoteConfigOptions describes whose config files a RemoteConfig loads and where it keeps
+// them.
+type RemoteConfigOptions struct {
+	// App and Server own the files, SetDivision is the set of the server, if any.
+	App         string
+	Server      string
+	SetDivision string
+	// Dir keeps the downloaded files, so that the server can start when the config
+	// service is down. The current directory when not set.
+	Dir string
+}
+
+// RemoteConfig downloads the config files of a server from the TARS config service, usually
+// tars.tarsconfig.ConfigObj, and tells subscribers when they are reloaded. It is safe for
+// concurrent use.
+type RemoteConfig struct {
+	options     RemoteConfigOptions
+	proxy       *ConfigFProxy
+	mutex       sync.Mutex
+	subscribers []func(filename, content string)
+}
+
+func NewRemoteConfig(obj string, options RemoteConfigOptions, opts ...ClientOption) *RemoteConfig {
+	if options.Dir == "" {
+		options.Dir = "."
+	}
+	return &RemoteConfig{
+		options: options,
+		proxy:   NewConfigFProxy(obj, 3*time.Second, opts...),
+	}
+}
+
+func (r *RemoteConfig) info(filename string) ConfigInfo {
+	return ConfigInfo{
+		Appname:     r.options.App,
+		Servername:  r.options.Server,
+		Filename:    filename,
+		Setdivision: r.options.SetDivision,
+	}
+}
+
+// Path returns where filename is kept locally.
+func (r *RemoteConfig) Path(filename string) string {
+	return filepath.Join(r.options.Dir, filename)
+}
+
+// ListConfig returns the names of the files the config service has for the server.
+func (r *RemoteConfig) ListConfig() ([]string, error) {
+	var files []string
+	ret, _, err := r.proxy.ListConfigByInfo(r.info(""), &files, nil)
+	if nil != err {
+		return nil, err
+	}
+	if ret != 0 {
+		return nil, fmt.Errorf("%w:list returned %d", ErrLoadConfig, ret)
+	}
+	return files, nil
+}
+
+// download fetches filename and saves it to Dir.
+func (r *RemoteConfig) download(filename string) (string, error) {
+	if filename == "" || filepath.Base(filename) != filename {
+		return "", fmt.Errorf("%w:%q", ErrInvalidConfigName, filename)
+	}
+	var content string
+	ret, _, err := r.proxy.LoadConfigByInfo(r.info(filename), &content, nil)
+	if nil != err {
+		return "", err
+	}
+	if ret != 0 {
+		return "", fmt.Errorf("%w:%s returned %d %s", ErrLoadConfig, filename, ret, content)
+	}
+	if err := os.MkdirAll(r.options.Dir, 0755); nil != err {
+		return "", err
+	}
+	tmp := r.Path(filename) + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); nil != err {
+		return "", err
+	}
+	if err := os.Rename(tmp, r.Path(filename)); nil != err {
+		os.Remove(tmp)
+		return "", err
+	}
+	return content, nil
+}
+
+// AddConfig downloads filename to Dir and returns its content. When the config service
+// cannot be reached, the copy kept in Dir by a previous download is returned instead.
+func (r *RemoteConfig) AddConfig(filename string) (string, error) {
+	content, err := r.download(filename)
+	if nil == err || errors.Is(err, ErrInvalidConfigName) || errors.Is(err, ErrLoadConfig) {
+		return content, err
+	}
+	cached, readErr := os.ReadFile(r.Path(filename))
+	if nil != readErr {
+		return "", err
+	}
+	logMsg(LogLevelWarn, "Failed to load config, using the local copy", Field{"file", filename}, Field{FieldError, err})
+	return string(cached), nil
+}
+
+// Subscribe calls f with the new content of every file reloaded with Reload.
+func (r *RemoteConfig) Subscribe(f func(filename, content string)) {
+	r.mutex.Lock()
+	r.subscribers = append(r.subscribers, f)
+	r.mutex.Unlock()
+}
+
+// Reload downloads filename again, as asked by an admin push, and passes it to the
+// subscribers. Unlike AddConfig it fails when the config service cannot be reached.
+func (r *RemoteConfig) Reload(filename string) (string, error) {
+	content, err := r.download(filename)
+	if nil != err {
+		return "", err
+	}
+	r.mutex.Lock()
+	subscribers := make([]func(filename, content string), len(r.subscribers))
+	copy(subscribers, r.subscribers)
+	r.mutex.Unlock()
+	for _, f := range subscribers {
+		f(filename, content)
+	}
+	return content, nil
+}
+
+func (r *RemoteConfig) Close() error {
+	r.proxy.TarsClient.Close()
+	return nil
+}
@@ -0,0 +1,121 @@
+package tarsgo
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// configStore is a stand-in ConfigObj serving files from memory.
+type configStore struct {
+	mutex sync.Mutex
+	files map[string]string
+}
+
+func (s *configStore) set(filename, content string) {
+	s.mutex.Lock()
+	s.files[filename] = content
+	s.mutex.Unlock()
+}
+
+func (s *configStore) ListConfig(app string, server string, vf *[]string, context map[string]string) (int32, map[string]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for filename := range s.files {
+		*vf = append(*vf, filename)
+	}
+	sort.Strings(*vf)
+	return 0, nil, nil
+}
+
+func (s *configStore) LoadConfig(app string, server string, filename string, config *string, context map[string]string) (int32, map[string]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	content, exist := s.files[filename]
+	if !exist {
+		*config = "no such file"
+		return -1, nil, nil
+	}
+	*config = content
+	return 0, nil, nil
+}
+
+func (s *configStore) LoadConfigByHost(appServerName string, filename string, host string, config *string, context map[string]string) (int32, map[string]string, error) {
+	return s.LoadConfig("", appServerName, filename, config, context)
+}
+
+func (s *configStore) CheckConfig(appServerName string, filename string, host string, result *string, context map[string]string) (int32, map[string]string, error) {
+	return 0, nil, nil
+}
+
+func (s *configStore) ListConfigByInfo(configInfo ConfigInfo, vf *[]string, context map[string]string) (int32, map[string]string, error) {
+	return s.ListConfig(configInfo.Appname, configInfo.Servername, vf, context)
+}
+
+func (s *configStore) LoadConfigByInfo(configInfo ConfigInfo, config *string, context map[string]string) (int32, map[string]string, error) {
+	if configInfo.Appname != "Test" || configInfo.Servername != "EchoServer" {
+		*config = "unknown server"
+		return -1, nil, nil
+	}
+	return s.LoadConfig(configInfo.Appname, configInfo.Servername, configInfo.Filename, config, context)
+}
+
+func (s *configStore) CheckConfigByInfo(configInfo ConfigInfo, result *string, context map[string]string) (int32, map[string]string, error) {
+	return 0, nil, nil
+}
+
+func (s *configStore) ListAllConfigByInfo(configInfo GetConfigListInfo, vf *[]string, context map[string]string) (int32, map[string]string, error) {
+	return s.ListConfig(configInfo.Appname, configInfo.Servername, vf, context)
+}
+
+func TestRemoteConfig(t *testing.T) {
+	store := &configStore{files: map[string]string{"EchoServer.conf": "<echo>\nsize=1\n</echo>\n", "app.conf": ""}}
+	s, configObj := startTestServer(t, "tars.tarsconfig.ConfigObj", &ConfigFDispatcher{store})
+	dir := t.TempDir()
+	options := RemoteConfigOptions{App: "Test", Server: "EchoServer", Dir: dir}
+
+	r := NewRemoteConfig(configObj, options)
+	if files, err := r.ListConfig(); nil != err || len(files) != 2 || files[0] != "EchoServer.conf" {
+		t.Fatalf("listed %v, %v", files, err)
+	}
+	if content, err := r.AddConfig("EchoServer.conf"); nil != err || content != "<echo>\nsize=1\n</echo>\n" {
+		t.Fatalf("loaded %q, %v", content, err)
+	}
+	if b, err := os.ReadFile(r.Path("EchoServer.conf")); nil != err || string(b) != "<echo>\nsize=1\n</echo>\n" {
+		t.Fatalf("saved %q, %v", b, err)
+	}
+	if _, err := r.AddConfig("missing.conf"); !errors.Is(err, ErrLoadConfig) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.AddConfig("../EchoServer.conf"); !errors.Is(err, ErrInvalidConfigName) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var reloaded []string
+	r.Subscribe(func(filename, content string) {
+		reloaded = append(reloaded, filename+":"+content)
+	})
+	store.set("EchoServer.conf", "<echo>\nsize=2\n</echo>\n")
+	if _, err := r.Reload("EchoServer.conf"); nil != err {
+		t.Fatal(err)
+	}
+	if len(reloaded) != 1 || reloaded[0] != "EchoServer.conf:<echo>\nsize=2\n</echo>\n" {
+		t.Fatalf("subscriber got %q", reloaded)
+	}
+	r.Close()
+
+	// a server starting while the config service is down uses the local copy
+	s.Close()
+	offline := NewRemoteConfig(configObj, options)
+	offline.proxy.TarsClient.Timeout = 100 * time.Millisecond
+	defer offline.Close()
+	if content, err := offline.AddConfig("EchoServer.conf"); nil != err || content != "<echo>\nsize=2\n</echo>\n" {
+		t.Fatalf("offline load returned %q, %v", content, err)
+	}
+	if _, err := offline.Reload("EchoServer.conf"); nil == err {
+		t.Fatal("reload succeeded while the config service is down")
+	}
+}
@@ -0,0 +1,221 @@
+package tarsgo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Transport values of EndpointF.Istcp.
+const (
+	EndpointUDP  = int32(0)
+	EndpointTCP  = int32(1)
+	EndpointSSL  = int32(2)
+	EndpointUnix = int32(3)
+)
+
+var endpointSchemes = map[string]int32{
+	"udp":  EndpointUDP,
+	"tcp":  EndpointTCP,
+	"ssl":  EndpointSSL,
+	"unix": EndpointUnix,
+}
+
+// ParseEndpoint parses an endpoint such as "tcp -h 127.0.0.1 -p 10000 -t 60000".
+//
+// The scheme is one of tcp, udp, ssl or unix; unix endpoints carry the socket path in -h.
+// IPv6 hosts may be written with or without brackets.
+func ParseEndpoint(s string) (EndpointF, error) {
+	e := EndpointF{}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return e, fmt.Errorf("empty endpoint")
+	}
+	istcp, ok := endpointSchemes[strings.ToLower(fields[0])]
+	if !ok {
+		return e, fmt.Errorf("unknown scheme %q in endpoint %q", fields[0], s)
+	}
+	e.Istcp = istcp
+	options := fields[1:]
+	for i := 0; i < len(options); i += 2 {
+		opt := options[i]
+		if !strings.HasPrefix(opt, "-") {
+			return e, fmt.Errorf("unexpected %q in endpoint %q, expected an option", opt, s)
+		}
+		if i+1 >= len(options) {
+			return e, fmt.Errorf("missing value for option %s in endpoint %q", opt, s)
+		}
+		value := options[i+1]
+		var err error
+		switch opt {
+		case "-h":
+			e.Host = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		case "-p":
+			e.Port, err = parseEndpointInt32(value)
+			if nil == err && (e.Port < 0 || e.Port > 65535) {
+				err = fmt.Errorf("port out of range")
+			}
+		case "-t":
+			e.Timeout, err = parseEndpointInt32(value)
+		case "-g":
+			e.Grid, err = parseEndpointInt32(value)
+		case "-q":
+			e.Qos, err = parseEndpointInt32(value)
+		case "-f":
+			e.GridFlag, err = parseEndpointInt32(value)
+		case "-w":
+			e.Weight, err = parseEndpointInt32(value)
+		case "-v":
+			e.WeightType, err = parseEndpointInt32(value)
+		case "-l":
+			e.Cpuload, err = parseEndpointInt32(value)
+		case "-m":
+			e.Sampletime, err = strconv.ParseInt(value, 10, 64)
+		case "-d":
+			e.ContainerName = value
+		default:
+			logMsg(LogLevelWarn, "Unknown endpoint arg", Field{"arg", opt}, Field{FieldEndpoint, s})
+		}
+		if nil != err {
+			return e, fmt.Errorf("invalid value %q for option %s in endpoint %q: %v", value, opt, s, err)
+		}
+	}
+	if e.Host == "" {
+		return e, fmt.Errorf("missing host (-h) in endpoint %q", s)
+	}
+	return e, nil
+}
+
+func parseEndpointInt32(s string) (int32, error) {
+	i, err := strconv.ParseInt(s, 10, 32)
+	return int32(i), err
+}
+
+// Scheme returns the endpoint scheme used in endpoint strings.
+func (p *EndpointF) Scheme() string {
+	switch p.Istcp {
+	case EndpointUDP:
+		return "udp"
+	case EndpointSSL:
+		return "ssl"
+	case EndpointUnix:
+		return "unix"
+	default:
+		return "tcp"
+	}
+}
+
+// Address returns the address to dial, host:port or the socket path of unix endpoints.
+func (p *EndpointF) Address() string {
+	if p.Istcp == EndpointUnix {
+		return p.Host
+	}
+	return net.JoinHostPort(p.Host, strconv.Itoa(int(p.Port)))
+}
+
+// String formats the endpoint so that ParseEndpoint returns it unchanged.
+func (p *EndpointF) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.Scheme())
+	sb.WriteString(" -h ")
+	sb.WriteString(p.Host)
+	if p.Istcp != EndpointUnix || p.Port != 0 {
+		fmt.Fprintf(&sb, " -p %d", p.Port)
+	}
+	for _, opt := range []struct {
+		name  string
+		value int64
+	}{
+		{"-t", int64(p.Timeout)},
+		{"-g", int64(p.Grid)},
+		{"-q", int64(p.Qos)},
+		{"-f", int64(p.GridFlag)},
+		{"-w", int64(p.Weight)},
+		{"-v", int64(p.WeightType)},
+		{"-l", int64(p.Cpuload)},
+		{"-m", p.Sampletime},
+	} {
+		if opt.value != 0 {
+			fmt.Fprintf(&sb, " %s %d", opt.name, opt.value)
+		}
+	}
+	if p.ContainerName != "" {
+		sb.WriteString(" -d ")
+		sb.WriteString(p.ContainerName)
+	}
+	return sb.String()
+}
+
+// ParseProxy parses a proxy string "Servant@ep1:ep2"; without '@' only the servant is returned.
+//
+// Endpoints are split on colons followed by a scheme, so IPv6 hosts are kept intact.
+// Invalid endpoints are skipped: the valid ones are returned along with an error
+// describing the rest.
+func ParseProxy(s string) (string, []EndpointF, error) {
+	s = strings.TrimSpace(s)
+	idx := strings.Index(s, "@")
+	if idx < 0 {
+		if s == "" {
+			return "", nil, fmt.Errorf("empty servant name")
+		}
+		return s, nil, nil
+	}
+	servant := strings.TrimSpace(s[:idx])
+	if servant == "" {
+		return "", nil, fmt.Errorf("missing servant name in %q", s)
+	}
+	var endpoints []EndpointF
+	var errs []error
+	for _, item := range splitEndpoints(s[idx+1:]) {
+		e, err := ParseEndpoint(item)
+		if nil != err {
+			errs = append(errs, err)
+			continue
+		}
+		endpoints = append(endpoints, e)
+	}
+	if len(endpoints) == 0 {
+		errs = append(errs, fmt.Errorf("no endpoint in %q", s))
+	}
+	return servant, endpoints, errors.Join(errs...)
+}
+
+// FormatProxy is the reverse of ParseProxy.
+func FormatProxy(servant string, endpoints []EndpointF) string {
+	items := make([]string, len(endpoints))
+	for i := range endpoints {
+		items[i] = endpoints[i].String()
+	}
+	if len(items) == 0 {
+		return servant
+	}
+	return servant + "@" + strings.Join(items, ":")
+}
+
+func splitEndpoints(s string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != ':' {
+			continue
+		}
+		fields := strings.Fields(s[i+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		if _, ok := endpointSchemes[strings.ToLower(fields[0])]; ok {
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, s[start:])
+	result := items[:0]
+	for _, item := range items {
+		if strings.TrimSpace(item) != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
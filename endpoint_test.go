@@ -0,0 +1,73 @@
+package tarsgo
+
+import (
+	"testing"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	for _, s := range []string{
+		"tcp -h 127.0.0.1 -p 10000",
+		"udp -h 10.0.0.1 -p 10001 -t 60000",
+		"ssl -h tars.example.com -p 443 -t 3000 -g 1 -q 2 -f 1 -w 50 -v 1 -l 30 -m 1500000000 -d container-1",
+		"tcp -h ::1 -p 10000",
+		"unix -h /var/run/tars.sock",
+	} {
+		e, err := ParseEndpoint(s)
+		if nil != err {
+			t.Fatalf("ParseEndpoint(%q): %v", s, err)
+		}
+		if e.String() != s {
+			t.Fatalf("round trip of %q returned %q", s, e.String())
+		}
+	}
+
+	e, err := ParseEndpoint("TCP -h [fe80::1] -p 80")
+	if nil != err || e.Host != "fe80::1" || e.Address() != "[fe80::1]:80" {
+		t.Fatalf("unexpected endpoint %+v, %v", e, err)
+	}
+
+	for _, s := range []string{
+		"",
+		"http -h 127.0.0.1 -p 80",
+		"tcp -h 127.0.0.1 -p",
+		"tcp -h 127.0.0.1 -p abc",
+		"tcp -h 127.0.0.1 -p 70000",
+		"tcp -p 10000",
+		"tcp 127.0.0.1",
+	} {
+		if _, err := ParseEndpoint(s); nil == err {
+			t.Fatalf("ParseEndpoint(%q) should fail", s)
+		}
+	}
+}
+
+func TestParseProxy(t *testing.T) {
+	servant, endpoints, err := ParseProxy("Test.HelloServer.HelloObj@tcp -h ::1 -p 10000:udp -h 127.0.0.1 -p 10001 -t 3000: unix -h /tmp/hello.sock")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if servant != "Test.HelloServer.HelloObj" || len(endpoints) != 3 {
+		t.Fatalf("unexpected %s %v", servant, endpoints)
+	}
+	if endpoints[0].Host != "::1" || endpoints[1].Istcp != EndpointUDP || endpoints[2].Host != "/tmp/hello.sock" {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+	s := FormatProxy(servant, endpoints)
+	if s != "Test.HelloServer.HelloObj@tcp -h ::1 -p 10000:udp -h 127.0.0.1 -p 10001 -t 3000:unix -h /tmp/hello.sock" {
+		t.Fatalf("unexpected proxy string %s", s)
+	}
+
+	servant, endpoints, err = ParseProxy("Test.HelloServer.HelloObj")
+	if nil != err || servant != "Test.HelloServer.HelloObj" || nil != endpoints {
+		t.Fatalf("unexpected %s %v %v", servant, endpoints, err)
+	}
+	servant, endpoints, err = ParseProxy("Test.HelloServer.HelloObj@tcp -h 127.0.0.1 -p x:tcp -h 127.0.0.1 -p 10001:tcp -p 10002")
+	if nil == err || servant != "Test.HelloServer.HelloObj" || len(endpoints) != 1 || endpoints[0].Port != 10001 {
+		t.Fatalf("invalid endpoints were not skipped: %s %v %v", servant, endpoints, err)
+	}
+	for _, s := range []string{"", "@tcp -h 127.0.0.1 -p 1", "Test.HelloServer.HelloObj@", "Obj@tcp -h 127.0.0.1 -p x"} {
+		if _, _, err := ParseProxy(s); nil == err {
+			t.Fatalf("ParseProxy(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tarsgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Return codes of the TARS protocol, carried in ResponsePacket.IRet.
+const (
+	TarsServerSuccess       = 0   // success
+	TarsServerDecodeErr     = -1  // server failed to decode the request
+	TarsServerEncodeErr     = -2  // server failed to encode the response
+	TarsServerNoFuncErr     = -3  // servant has no such function
+	TarsServerNoServantErr  = -4  // server has no such servant
+	TarsServerResetGrid     = -5  // grey state of the server mismatches
+	TarsServerQueueTimeout  = -6  // request timed out in the server queue
+	TarsAsyncCallTimeout    = -7  // asynchronous call timed out
+	TarsInvokeTimeout       = -7  // call timed out
+	TarsProxyConnectErr     = -8  // proxy failed to connect
+	TarsServerOverload      = -9  // server is overloaded
+	TarsAdapterNull         = -10 // no adapter available for the call
+	TarsInvokeByInvalidEset = -11 // call from a set the server does not serve
+	TarsClientDecodeErr     = -12 // client failed to decode the response
+	TarsSendRequestErr      = -13 // client failed to send the request
+	TarsServerUnknownErr    = -99 // unknown server error
+)
+
+var codeNames = map[int32]string{
+	TarsServerSuccess:       "success",
+	TarsServerDecodeErr:     "server decode error",
+	TarsServerEncodeErr:     "server encode error",
+	TarsServerNoFuncErr:     "no such function",
+	TarsServerNoServantErr:  "no such servant",
+	TarsServerResetGrid:     "reset grid",
+	TarsServerQueueTimeout:  "server queue timeout",
+	TarsInvokeTimeout:       "invoke timeout",
+	TarsProxyConnectErr:     "proxy connect error",
+	TarsServerOverload:      "server overload",
+	TarsAdapterNull:         "adapter null",
+	TarsInvokeByInvalidEset: "invoke by invalid set",
+	TarsClientDecodeErr:     "client decode error",
+	TarsSendRequestErr:      "send request error",
+	TarsServerUnknownErr:    "unknown server error",
+}
+
+// TarsError is a failure reported by a server through ResponsePacket.IRet. Dispatchers
+// may return one to answer with a specific code.
+type TarsError struct {
+	Code int32
+	Desc string
+}
+
+func NewTarsError(code int32, desc string) *TarsError {
+	return &TarsError{Code: code, Desc: desc}
+}
+
+func (e *TarsError) Error() string {
+	name, ok := codeNames[e.Code]
+	if !ok {
+		name = "error"
+	}
+	if e.Desc == "" {
+		return fmt.Sprintf("Tars %s:%d", name, e.Code)
+	}
+	return fmt.Sprintf("Tars %s:%d %s", name, e.Code, e.Desc)
+}
+
+// ErrorCode returns the code of a *TarsError in err's chain, TarsServerSuccess for nil,
+// TarsInvokeTimeout for ErrTarsRPCTimeout and TarsServerUnknownErr for any other error.
+func ErrorCode(err error) int32 {
+	if nil == err {
+		return TarsServerSuccess
+	}
+	var tarsErr *TarsError
+	if errors.As(err, &tarsErr) {
+		return tarsErr.Code
+	}
+	if errors.Is(err, ErrTarsRPCTimeout) {
+		return TarsInvokeTimeout
+	}
+	return TarsServerUnknownErr
+}
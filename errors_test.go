@@ -0,0 +1,70 @@
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingDispatcher fails every call with the error it wraps.
+type failingDispatcher struct {
+	err error
+}
+
+func (d failingDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	resp.SBuffer = []byte("partial")
+	return d.err
+}
+
+func TestTarsError(t *testing.T) {
+	_, overloaded := startTestServer(t, "Test.EchoServer.EchoObj", failingDispatcher{NewTarsError(TarsServerOverload, "busy")})
+	_, failing := startTestServer(t, "Test.EchoServer.EchoObj", failingDispatcher{errors.New("boom")})
+	r, err := NewRegistry(&RegistryConfig{})
+	if nil != err {
+		t.Fatal(err)
+	}
+	_, query := startTestServer(t, "tars.tarsregistry.QueryObj", r)
+
+	for _, tc := range []struct {
+		obj, funcName string
+		code          int32
+		desc          string
+	}{
+		{overloaded, "echo", TarsServerOverload, "busy"},
+		{failing, "echo", TarsServerUnknownErr, "boom"},
+		{strings.Replace(failing, "EchoObj", "MissingObj", 1), "echo", TarsServerNoServantErr, "No servant:Test.EchoServer.MissingObj"},
+		{query, "missing", TarsServerNoFuncErr, "func mismatch:missing"},
+		{query, "findObjectById", TarsServerDecodeErr, ""},
+	} {
+		c := NewClient(tc.obj, time.Second)
+		resp, err := c.Invoke(JCENORMAL, tc.funcName, new(bytes.Buffer), nil)
+		var tarsErr *TarsError
+		if !errors.As(err, &tarsErr) || tarsErr.Code != tc.code || ErrorCode(err) != tc.code {
+			t.Fatalf("%s: expected code %d, got %v", tc.obj, tc.code, err)
+		}
+		if tc.desc != "" && tarsErr.Desc != tc.desc {
+			t.Fatalf("%s: unexpected description %q", tc.obj, tarsErr.Desc)
+		}
+		if nil == resp || len(resp.SBuffer) != 0 {
+			t.Fatalf("%s: unexpected response %+v", tc.obj, resp)
+		}
+		c.Close()
+	}
+
+	_, overloadedQuery := startTestServer(t, "tars.tarsregistry.QueryObj", failingDispatcher{NewTarsError(TarsServerOverload, "busy")})
+	q := NewQueryFProxy(overloadedQuery, time.Second)
+	defer q.TarsClient.Close()
+	if endpoints, _, err := q.FindObjectById("Test.EchoServer.EchoObj", nil); ErrorCode(err) != TarsServerOverload || nil != endpoints {
+		t.Fatalf("proxy returned %v, %v", endpoints, err)
+	}
+	if ErrorCode(nil) != TarsServerSuccess {
+		t.Fatal("nil error has a code")
+	}
+	if code := ErrorCode(fmt.Errorf("wrapped:%w", ErrTarsRPCTimeout)); code != TarsInvokeTimeout {
+		t.Fatalf("unexpected code %d for timeout", code)
+	}
+}
@@ -0,0 +1,44 @@
+package tarsgo
+
+import (
+	"sync"
+	"time"
+)
+
+// flushLoop calls flush every interval in the background and a last time when closed.
+// It drives the reporters that batch data for the TARS services.
+type flushLoop struct {
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func startFlushLoop(interval time.Duration, flush func()) *flushLoop {
+	f := &flushLoop{done: make(chan struct{})}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				flush()
+			case <-f.done:
+				flush()
+				return
+			}
+		}
+	}()
+	return f
+}
+
+// close stops the loop after its last flush and then releases the client it flushed to.
+// Only the first call has an effect.
+func (f *flushLoop) close(release func() error) {
+	f.closeOnce.Do(func() {
+		close(f.done)
+		f.wg.Wait()
+		release()
+	})
+}
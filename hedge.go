@@ -0,0 +1,153 @@
+package tarsgo
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	hedgeWindow     = 256
+	hedgeMinSamples = 16
+)
+
+// HedgePolicy sends a second copy of a call to another endpoint when the first has not
+// been answered after the given percentile of recent latencies of the same function.
+// Only functions marked with WithIdempotent are hedged.
+type HedgePolicy struct {
+	// Percentile of the latencies to wait, 0.95 when not set.
+	Percentile float64
+	// MinDelay and MaxDelay bound the wait; MaxDelay is also used until enough
+	// latencies are known. MaxDelay is half the client timeout when not set.
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+// WithHedging enables hedged calls for the idempotent functions of a client.
+func WithHedging(policy HedgePolicy) ClientOption {
+	return func(c *Client) {
+		if policy.Percentile <= 0 || policy.Percentile > 1 {
+			policy.Percentile = 0.95
+		}
+		c.hedgePolicy = &policy
+		c.latencies = make(map[string]*latencyWindow)
+	}
+}
+
+// latencyWindow keeps the latest latencies of a function and their percentile.
+type latencyWindow struct {
+	mutex   sync.Mutex
+	samples []time.Duration
+	next    int
+	fresh   int
+	delay   time.Duration
+}
+
+func (w *latencyWindow) add(d time.Duration, policy *HedgePolicy) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.samples) < hedgeWindow {
+		w.samples = append(w.samples, d)
+	} else {
+		w.samples[w.next] = d
+		w.next = (w.next + 1) % hedgeWindow
+	}
+	w.fresh++
+	if len(w.samples) < hedgeMinSamples || w.fresh < hedgeMinSamples && w.delay > 0 {
+		return
+	}
+	w.fresh = 0
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	w.delay = sorted[int(float64(len(sorted)-1)*policy.Percentile)]
+}
+
+func (w *latencyWindow) hedgeDelay(policy *HedgePolicy, timeout time.Duration) time.Duration {
+	maxDelay := policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = timeout / 2
+	}
+	w.mutex.Lock()
+	d := w.delay
+	w.mutex.Unlock()
+	if d == 0 || d > maxDelay {
+		d = maxDelay
+	}
+	if d < policy.MinDelay {
+		d = policy.MinDelay
+	}
+	return d
+}
+
+func (c *Client) latencyOf(funcName string) *latencyWindow {
+	c.sessionMutex.Lock()
+	defer c.sessionMutex.Unlock()
+	w, exist := c.latencies[funcName]
+	if !exist {
+		w = new(latencyWindow)
+		c.latencies[funcName] = w
+	}
+	return w
+}
+
+type hedgeResult struct {
+	rc   *rpcChannel
+	resp *ResponsePacket
+	err  error
+}
+
+// hedgedCall calls packet on rc and, if no response arrived within the hedge delay, sends
+// a copy to another endpoint. The first response wins and the other call is abandoned.
+// It returns the channel the result came from.
+func (c *Client) hedgedCall(ctx context.Context, rc *rpcChannel, packet *RequestPacket, deadline time.Time, tried map[*connPool]bool) (*rpcChannel, *ResponsePacket, error) {
+	w := c.latencyOf(packet.SFuncName)
+	start := time.Now()
+	delay := w.hedgeDelay(c.hedgePolicy, c.Timeout)
+	if delay <= 0 {
+		resp, err := c.call(rc, packet, deadline, ctx.Done())
+		if nil == err {
+			w.add(time.Since(start), c.hedgePolicy)
+		}
+		return rc, resp, err
+	}
+	results := make(chan hedgeResult, 2)
+	cancel := make(chan struct{})
+	defer close(cancel)
+	send := func(rc *rpcChannel, packet *RequestPacket) {
+		// closing cancel on return abandons whichever call is still running
+		c.goroutine(func() {
+			resp, err := c.call(rc, packet, deadline, cancel)
+			results <- hedgeResult{rc, resp, err}
+		})
+	}
+	send(rc, packet)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	calls := 1
+	var last hedgeResult
+	for calls > 0 {
+		select {
+		case r := <-results:
+			calls--
+			if nil == r.err {
+				w.add(time.Since(start), c.hedgePolicy)
+				return r.rc, r.resp, nil
+			}
+			last = r
+		case <-ctx.Done():
+			return rc, nil, errCallCancelled
+		case <-timer.C:
+			pools, _ := c.getPools()
+			avoid := map[*connPool]bool{rc.pool: true}
+			if hedge := c.pickRPCChannel(pools, avoid); nil != hedge && hedge.pool != rc.pool {
+				tried[hedge.pool] = true
+				copied := *packet
+				copied.IRequestId = c.nextRequestID()
+				send(hedge, &copied)
+				calls++
+			}
+		}
+	}
+	return last.rc, last.resp, last.err
+}
@@ -0,0 +1,72 @@
+package tarsgo
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer collects log output written from several goroutines.
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestHedging(t *testing.T) {
+	var logs syncBuffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	_, slow := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(200*time.Millisecond))
+	_, fast := startTestServer(t, "Test.EchoServer.EchoObj", echoDispatcher{})
+	_, endpoints, _ := ParseProxy(fast)
+	c := NewClient(slow+":"+endpoints[0].String(), time.Second, WithMaxConn(1),
+		WithIdempotent("echo"), WithHedging(HedgePolicy{MaxDelay: 20 * time.Millisecond}))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+		if nil != err || string(resp.SBuffer) != "hello" {
+			t.Fatalf("hedged call returned %v, %v", resp, err)
+		}
+		if time.Since(start) > 150*time.Millisecond {
+			t.Fatalf("hedged call took %v", time.Since(start))
+		}
+	}
+	// let the slow server answer the abandoned copies
+	time.Sleep(300 * time.Millisecond)
+	if strings.Contains(logs.String(), "Missing session") {
+		t.Fatalf("abandoned responses were logged:\n%s", logs.String())
+	}
+
+	w := new(latencyWindow)
+	policy := &HedgePolicy{Percentile: 0.5, MinDelay: time.Millisecond, MaxDelay: time.Second}
+	if d := w.hedgeDelay(policy, 3*time.Second); d != time.Second {
+		t.Fatalf("unexpected delay %v without samples", d)
+	}
+	for i := 1; i <= 100; i++ {
+		w.add(time.Duration(i)*time.Millisecond, policy)
+	}
+	if d := w.hedgeDelay(policy, 3*time.Second); d < 40*time.Millisecond || d > 60*time.Millisecond {
+		t.Fatalf("unexpected median %v", d)
+	}
+	if d := new(latencyWindow).hedgeDelay(&HedgePolicy{}, 3*time.Second); d != 1500*time.Millisecond {
+		t.Fatalf("unexpected delay %v without MaxDelay", d)
+	}
+}
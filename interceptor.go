@@ -0,0 +1,50 @@
+package tarsgo
+
+import (
+	"context"
+)
+
+// Invoker sends a request and returns its response.
+type Invoker func(ctx context.Context, req *RequestPacket) (*ResponsePacket, error)
+
+// ClientInterceptor wraps every call of a Client. It may change req, including its
+// servant, function, Context and Status, before passing it to next, inspect the response
+// and error next returns, or answer the call itself without calling next.
+type ClientInterceptor func(ctx context.Context, req *RequestPacket, next Invoker) (*ResponsePacket, error)
+
+// WithInterceptors adds interceptors to a client; the first one added is the outermost.
+func WithInterceptors(interceptors ...ClientInterceptor) ClientOption {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+func chainClientInterceptors(interceptors []ClientInterceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, req *RequestPacket) (*ResponsePacket, error) {
+			return interceptor(ctx, req, next)
+		}
+	}
+	return invoker
+}
+
+// Handler serves one request by filling in resp. A returned *TarsError sets the code of
+// the response, any other error answers with TarsServerUnknownErr.
+type Handler func(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error
+
+// ServerInterceptor wraps the dispatch of every request of a Server. It sees the decoded
+// request, including SServantName, SFuncName, Context, Status and ITimeout, and finds
+// the caller's address with CurrentFromContext. It may reject the request by returning
+// an error without calling next, enrich ctx or req, and change resp once next returned.
+type ServerInterceptor func(ctx context.Context, req *RequestPacket, resp *ResponsePacket, next Handler) error
+
+func chainServerInterceptors(interceptors []ServerInterceptor, handler Handler) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+			return interceptor(ctx, req, resp, next)
+		}
+	}
+	return handler
+}
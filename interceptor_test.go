@@ -0,0 +1,120 @@
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// contextDispatcher answers with the request context entry named by the request buffer.
+type contextDispatcher struct{}
+
+func (contextDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	resp.SBuffer = []byte(req.Context[string(req.SBuffer)] + "|" + req.Status["trace"])
+	return nil
+}
+
+func TestClientInterceptors(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", contextDispatcher{})
+	var order []string
+	record := func(name string) ClientInterceptor {
+		return func(ctx context.Context, req *RequestPacket, next Invoker) (*ResponsePacket, error) {
+			order = append(order, name+">"+req.SFuncName)
+			resp, err := next(ctx, req)
+			order = append(order, fmt.Sprintf("%s<%d", name, ErrorCode(err)))
+			return resp, err
+		}
+	}
+	auth := func(ctx context.Context, req *RequestPacket, next Invoker) (*ResponsePacket, error) {
+		if nil == req.Context {
+			req.Context = make(map[string]string)
+		}
+		req.Context["auth"] = "secret"
+		req.Status = map[string]string{"trace": "abc"}
+		return next(ctx, req)
+	}
+	cache := func(ctx context.Context, req *RequestPacket, next Invoker) (*ResponsePacket, error) {
+		if req.SFuncName == "cached" {
+			return &ResponsePacket{SBuffer: []byte("from cache")}, nil
+		}
+		return next(ctx, req)
+	}
+	c := NewClient(obj, time.Second, WithInterceptors(record("outer"), auth), WithInterceptors(cache, record("inner")))
+	defer c.Close()
+
+	resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("auth"), nil)
+	if nil != err || string(resp.SBuffer) != "secret|abc" {
+		t.Fatalf("unexpected response %v, %v", resp, err)
+	}
+	if strings.Join(order, " ") != "outer>echo inner>echo inner<0 outer<0" {
+		t.Fatalf("unexpected order %v", order)
+	}
+	order = nil
+	resp, err = c.Invoke(JCENORMAL, "cached", bytes.NewBufferString("auth"), nil)
+	if nil != err || string(resp.SBuffer) != "from cache" || strings.Join(order, " ") != "outer>cached outer<0" {
+		t.Fatalf("call was not short-circuited: %v, %v, %v", resp, err, order)
+	}
+}
+
+func TestInvokeContext(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(500*time.Millisecond))
+	c := NewClient(obj, time.Second)
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := c.InvokeContext(ctx, JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); err != context.Canceled {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.InvokeContext(ctx, JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil == err {
+		t.Fatal("expected the context deadline to end the call")
+	}
+	if time.Since(start) > 300*time.Millisecond {
+		t.Fatalf("calls ignored their context for %v", time.Since(start))
+	}
+}
+
+func TestServerInterceptors(t *testing.T) {
+	s, obj := startTestServer(t, "Test.EchoServer.EchoObj", echoDispatcher{})
+	var seen []string
+	s.AddInterceptor(func(ctx context.Context, req *RequestPacket, resp *ResponsePacket, next Handler) error {
+		current, ok := CurrentFromContext(ctx)
+		if !ok || nil == current.RemoteAddr {
+			return errors.New("no caller address")
+		}
+		if req.Context["auth"] != "secret" {
+			return NewTarsError(TarsServerNoFuncErr, "denied")
+		}
+		start := time.Now()
+		err := next(ctx, req, resp)
+		resp.Status = map[string]string{"cost": time.Since(start).String()}
+		return err
+	}, func(ctx context.Context, req *RequestPacket, resp *ResponsePacket, next Handler) error {
+		seen = append(seen, fmt.Sprintf("%s.%s %d", req.SServantName, req.SFuncName, req.ITimeout))
+		err := next(ctx, req, resp)
+		resp.SBuffer = append(resp.SBuffer, '!')
+		return err
+	})
+
+	c := NewClient(obj, time.Second)
+	defer c.Close()
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); ErrorCode(err) != TarsServerNoFuncErr {
+		t.Fatalf("request was not rejected: %v", err)
+	}
+	resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), map[string]string{"auth": "secret"})
+	if nil != err || string(resp.SBuffer) != "hello!" || resp.Status["cost"] == "" {
+		t.Fatalf("unexpected response %+v, %v", resp, err)
+	}
+	if len(seen) != 1 || seen[0] != "Test.EchoServer.EchoObj.echo 1000" {
+		t.Fatalf("unexpected requests %v", seen)
+	}
+	if _, err = NewClient(strings.Replace(obj, "EchoObj", "MissingObj", 1), time.Second).Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), map[string]string{"auth": "secret"}); ErrorCode(err) != TarsServerNoServantErr {
+		t.Fatalf("expected missing servant, got %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package tarsgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogLevel is the severity of a message, as used by TARS log levels.
+type LogLevel int32
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+	// LogLevelNone disables logging when given to SetLogLevel.
+	LogLevelNone
+)
+
+var logLevelNames = []string{"DEBUG", "INFO", "WARN", "ERROR", "NONE"}
+
+var ErrInvalidLogLevel = errors.New("Invalid log level")
+
+func (l LogLevel) String() string {
+	if l < LogLevelDebug || l > LogLevelNone {
+		return "LEVEL" + strconv.Itoa(int(l))
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel parses the name of a level such as "DEBUG", ignoring case.
+func ParseLogLevel(s string) (LogLevel, error) {
+	for i, name := range logLevelNames {
+		if strings.EqualFold(s, name) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogLevelNone, fmt.Errorf("%w:%s", ErrInvalidLogLevel, s)
+}
+
+// Field is a named value attached to a message, such as the endpoint of a call.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Keys of the fields attached to the messages of the package.
+const (
+	FieldServant   = "servant"
+	FieldFunc      = "func"
+	FieldEndpoint  = "endpoint"
+	FieldRequestID = "requestId"
+	FieldError     = "error"
+)
+
+// Logger receives the messages logged by the package that pass the level set with
+// SetLogLevel. It may be called from several goroutines at once.
+type Logger interface {
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
+// formatFields formats fields as " key=value" pairs, quoting values that need it.
+func formatFields(fields []Field) string {
+	var b strings.Builder
+	for _, f := range fields {
+		value := fmt.Sprint(f.Value)
+		if value == "" || strings.ContainsAny(value, " \t\n\"=|") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(" " + f.Key + "=" + value)
+	}
+	return b.String()
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Log(level LogLevel, msg string, fields ...Field) {
+	log.Print(level.String() + " " + msg + formatFields(fields))
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger sends messages to l, with their fields as attributes.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+func (s slogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	var slevel slog.Level
+	switch level {
+	case LogLevelDebug:
+		slevel = slog.LevelDebug
+	case LogLevelInfo:
+		slevel = slog.LevelInfo
+	case LogLevelWarn:
+		slevel = slog.LevelWarn
+	default:
+		slevel = slog.LevelError
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	s.l.LogAttrs(context.Background(), slevel, msg, attrs...)
+}
+
+type loggerHolder struct {
+	Logger
+}
+
+var (
+	logLevel      = int32(LogLevelInfo)
+	currentLogger atomic.Value
+)
+
+func init() {
+	currentLogger.Store(loggerHolder{stdLogger{}})
+}
+
+// SetLogger routes the messages of the package to l, or to the standard log package when
+// l is nil, which is the default. Clients created WithLogger use their own logger instead.
+func SetLogger(l Logger) {
+	if nil == l {
+		l = stdLogger{}
+	}
+	currentLogger.Store(loggerHolder{l})
+}
+
+// SetLogLevel drops messages below level, for every logger. It may be called at any time;
+// the default is LogLevelInfo.
+func SetLogLevel(level LogLevel) {
+	atomic.StoreInt32(&logLevel, int32(level))
+}
+
+func GetLogLevel() LogLevel {
+	return LogLevel(atomic.LoadInt32(&logLevel))
+}
+
+// logWith sends a message to l, or to the logger set with SetLogger when l is nil.
+func logWith(l Logger, level LogLevel, msg string, fields ...Field) {
+	if level < GetLogLevel() {
+		return
+	}
+	if nil == l {
+		l = currentLogger.Load().(loggerHolder).Logger
+	}
+	l.Log(level, msg, fields...)
+}
+
+func logMsg(level LogLevel, msg string, fields ...Field) {
+	logWith(nil, level, msg, fields...)
+}
+
+// WithLogger sends the messages about a client to l rather than to the logger set with
+// SetLogger.
+func WithLogger(l Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// log sends a message about c, tagged with its servant.
+func (c *Client) log(level LogLevel, msg string, fields ...Field) {
+	logWith(c.logger, level, msg, append([]Field{{FieldServant, c.servant}}, fields...)...)
+}
+
+type writerLogger struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+// NewWriterLogger writes every message to w in a single Write, as a line formatted like
+// TARS logs: "2006-01-02 15:04:05.000|pid|LEVEL|message key=value".
+func NewWriterLogger(w io.Writer) Logger {
+	return &writerLogger{w: w}
+}
+
+func (l *writerLogger) Log(level LogLevel, msg string, fields ...Field) {
+	line := fmt.Sprintf("%s|%d|%s|%s%s\n", time.Now().Format("2006-01-02 15:04:05.000"), os.Getpid(), level, strings.TrimRight(msg, "\n"), formatFields(fields))
+	l.mutex.Lock()
+	l.w.Write([]byte(line))
+	l.mutex.Unlock()
+}
+
+// RollWriter writes to a file and rolls it by size like TARS roll logs: when a write would
+// take "app.server.log" beyond maxSize it becomes "app.server1.log", the previous
+// "app.server1.log" becomes "app.server2.log" and so on, keeping at most maxFiles old files.
+type RollWriter struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+}
+
+func NewRollWriter(path string, maxSize int64, maxFiles int) (*RollWriter, error) {
+	w := &RollWriter{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := w.open(); nil != err {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RollWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); nil != err {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if nil != err {
+		return err
+	}
+	st, err := f.Stat()
+	if nil != err {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, st.Size()
+	return nil
+}
+
+// rollName returns the name of the i-th old file.
+func (w *RollWriter) rollName(i int) string {
+	ext := filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + strconv.Itoa(i) + ext
+}
+
+func (w *RollWriter) roll() error {
+	w.file.Close()
+	w.file = nil
+	if w.maxFiles <= 0 {
+		os.Remove(w.path)
+	} else {
+		os.Remove(w.rollName(w.maxFiles))
+		for i := w.maxFiles - 1; i >= 1; i-- {
+			os.Rename(w.rollName(i), w.rollName(i+1))
+		}
+		if err := os.Rename(w.path, w.rollName(1)); nil != err {
+			return err
+		}
+	}
+	return w.open()
+}
+
+func (w *RollWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if nil == w.file {
+		if err := w.open(); nil != err {
+			return 0, err
+		}
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.roll(); nil != err {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RollWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if nil == w.file {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// DayWriter writes to a file per day like TARS day logs, named "path_20060102.log" after
+// the day of the write.
+type DayWriter struct {
+	path  string
+	mutex sync.Mutex
+	day   string
+	file  *os.File
+	now   func() time.Time
+}
+
+func NewDayWriter(path string) *DayWriter {
+	return &DayWriter{path: path, now: time.Now}
+}
+
+func (w *DayWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	day := w.now().Format("20060102")
+	if day != w.day || nil == w.file {
+		if nil != w.file {
+			w.file.Close()
+			w.file = nil
+		}
+		if err := os.MkdirAll(filepath.Dir(w.path), 0755); nil != err {
+			return 0, err
+		}
+		f, err := os.OpenFile(w.path+"_"+day+".log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if nil != err {
+			return 0, err
+		}
+		w.file, w.day = f, day
+	}
+	return w.file.Write(p)
+}
+
+func (w *DayWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if nil == w.file {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,149 @@
+package tarsgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryLogger keeps the messages logged through it.
+type memoryLogger struct {
+	mutex sync.Mutex
+	msgs  []string
+}
+
+func (l *memoryLogger) Log(level LogLevel, msg string, fields ...Field) {
+	l.mutex.Lock()
+	l.msgs = append(l.msgs, level.String()+" "+msg+formatFields(fields))
+	l.mutex.Unlock()
+}
+
+func (l *memoryLogger) Messages() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.msgs...)
+}
+
+func TestLogLevel(t *testing.T) {
+	if level, err := ParseLogLevel("warn"); nil != err || level != LogLevelWarn {
+		t.Fatalf("parsed %v, %v", level, err)
+	}
+	if _, err := ParseLogLevel("verbose"); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	l := new(memoryLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+	defer SetLogLevel(GetLogLevel())
+	SetLogLevel(LogLevelWarn)
+	logMsg(LogLevelInfo, "dropped")
+	logMsg(LogLevelError, "kept", Field{"n", 1})
+	SetLogLevel(LogLevelNone)
+	logMsg(LogLevelError, "dropped")
+	// other tests may log concurrently
+	var kept bool
+	for _, msg := range l.Messages() {
+		if strings.Contains(msg, "dropped") {
+			t.Fatalf("unexpected message %q", msg)
+		}
+		kept = kept || msg == "ERROR kept n=1"
+	}
+	if !kept {
+		t.Fatalf("message missing from %q", l.Messages())
+	}
+}
+
+func TestClientLogger(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+
+	l := new(memoryLogger)
+	c := NewClient(fmt.Sprintf("Test.EchoServer.EchoObj@tcp -h 127.0.0.1 -p %d", addr.Port), 50*time.Millisecond, WithLogger(l))
+	defer c.Close()
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil == err {
+		t.Fatal("call to a closed port succeeded")
+	}
+	endpoint := fmt.Sprintf("endpoint=\"tcp -h 127.0.0.1 -p %d\"", addr.Port)
+	var connect bool
+	for _, msg := range l.Messages() {
+		connect = connect || strings.HasPrefix(msg, "ERROR Failed to connect server servant=Test.EchoServer.EchoObj "+endpoint+" error=")
+	}
+	if !connect {
+		t.Fatalf("dial failure missing from %q", l.Messages())
+	}
+
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.Log(LogLevelWarn, "Missing session", Field{FieldRequestID, int32(7)}, Field{FieldError, errors.New("boom")})
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); nil != err {
+		t.Fatal(err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "Missing session" || record[FieldRequestID] != 7.0 || record[FieldError] != "boom" {
+		t.Fatalf("unexpected record %v", record)
+	}
+}
+
+func TestRollWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Test.EchoServer.log")
+	w, err := NewRollWriter(path, 10, 2)
+	if nil != err {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"1111\n", "2222\n", "3333\n", "4444\n", "5555\n", "6666\n", "7777\n"} {
+		if _, err := w.Write([]byte(line)); nil != err {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	for name, content := range map[string]string{
+		"Test.EchoServer.log":  "7777\n",
+		"Test.EchoServer1.log": "5555\n6666\n",
+		"Test.EchoServer2.log": "3333\n4444\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if nil != err || string(b) != content {
+			t.Fatalf("%s: unexpected content %q, %v", name, b, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Test.EchoServer3.log")); !os.IsNotExist(err) {
+		t.Fatalf("too many files kept:%v", err)
+	}
+}
+
+func TestDayWriter(t *testing.T) {
+	dir := t.TempDir()
+	w := NewDayWriter(filepath.Join(dir, "Test.EchoServer_access"))
+	day := time.Date(2020, 1, 31, 23, 59, 0, 0, time.Local)
+	w.now = func() time.Time { return day }
+	logger := NewWriterLogger(w)
+	logger.Log(LogLevelInfo, "first")
+	day = day.Add(2 * time.Minute)
+	logger.Log(LogLevelError, "second\n")
+	w.Close()
+
+	for name, suffix := range map[string]string{
+		"Test.EchoServer_access_20200131.log": "|INFO|first\n",
+		"Test.EchoServer_access_20200201.log": "|ERROR|second\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if nil != err || !strings.HasSuffix(string(b), suffix) || strings.Count(string(b), "\n") != 1 {
+			t.Fatalf("%s: unexpected content %q, %v", name, b, err)
+		}
+	}
+}
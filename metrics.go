@@ -0,0 +1,267 @@
+package tarsgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of the calls of clients created with WithMetrics. It lets
+// any metrics library be plugged in; PrometheusMetrics is the built-in implementation.
+type Metrics interface {
+	// CallStarted and CallFinished bracket every call that passed the interceptors.
+	CallStarted(servant, funcName string)
+	// CallFinished reports the endpoint the call went to, the zero EndpointF if it was
+	// never sent, and its result code as returned by ErrorCode.
+	CallFinished(servant, funcName string, endpoint EndpointF, code int32, latency time.Duration)
+}
+
+// ClientTracker is implemented by Metrics that also report the connection pools of clients.
+type ClientTracker interface {
+	TrackClient(c *Client)
+}
+
+// WithMetrics reports the calls of a client to m. It may be given several times.
+func WithMetrics(m Metrics) ClientOption {
+	return func(c *Client) {
+		c.metrics = append(c.metrics, m)
+	}
+}
+
+// measure reports the calls made by invoker to c.metrics.
+func (c *Client) measure(invoker Invoker) Invoker {
+	return func(ctx context.Context, req *RequestPacket) (*ResponsePacket, error) {
+		servant, funcName := req.SServantName, req.SFuncName
+		peer, ok := ctx.Value(peerKey{}).(*EndpointF)
+		if !ok {
+			peer = new(EndpointF)
+			ctx = context.WithValue(ctx, peerKey{}, peer)
+		}
+		for _, m := range c.metrics {
+			m.CallStarted(servant, funcName)
+		}
+		start := time.Now()
+		resp, err := invoker(ctx, req)
+		code, latency := ErrorCode(err), time.Since(start)
+		for _, m := range c.metrics {
+			m.CallFinished(servant, funcName, *peer, code, latency)
+		}
+		return resp, err
+	}
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histograms.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type callKey struct {
+	servant, funcName, endpoint string
+}
+
+type callMetrics struct {
+	codes    map[int32]uint64
+	timeouts uint64
+	buckets  []uint64
+	sum      float64
+	count    uint64
+}
+
+// PrometheusMetrics collects client metrics in memory and serves them in the Prometheus
+// text format as an http.Handler. The zero value is ready to use.
+type PrometheusMetrics struct {
+	// Buckets are the latency histogram bounds, DefaultLatencyBuckets when not set. They
+	// must not be changed once calls were reported.
+	Buckets []float64
+
+	mutex    sync.Mutex
+	calls    map[callKey]*callMetrics
+	inFlight map[callKey]int64
+	clients  []*Client
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		Buckets:  DefaultLatencyBuckets,
+		calls:    make(map[callKey]*callMetrics),
+		inFlight: make(map[callKey]int64),
+	}
+}
+
+// initLocked prepares a zero PrometheusMetrics for its first call.
+func (m *PrometheusMetrics) initLocked() {
+	if nil == m.calls {
+		m.calls = make(map[callKey]*callMetrics)
+		m.inFlight = make(map[callKey]int64)
+	}
+	if nil == m.Buckets {
+		m.Buckets = DefaultLatencyBuckets
+	}
+}
+
+func (m *PrometheusMetrics) CallStarted(servant, funcName string) {
+	m.mutex.Lock()
+	m.initLocked()
+	m.inFlight[callKey{servant, funcName, ""}]++
+	m.mutex.Unlock()
+}
+
+func (m *PrometheusMetrics) CallFinished(servant, funcName string, endpoint EndpointF, code int32, latency time.Duration) {
+	key := callKey{servant, funcName, ""}
+	if endpoint.Host != "" {
+		key.endpoint = endpoint.String()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.initLocked()
+	m.inFlight[callKey{servant, funcName, ""}]--
+	cm, exist := m.calls[key]
+	if !exist {
+		cm = &callMetrics{codes: make(map[int32]uint64), buckets: make([]uint64, len(m.Buckets))}
+		m.calls[key] = cm
+	}
+	cm.codes[code]++
+	if code == TarsInvokeTimeout {
+		cm.timeouts++
+	}
+	seconds := latency.Seconds()
+	for i, bound := range m.Buckets {
+		if seconds <= bound {
+			cm.buckets[i]++
+		}
+	}
+	cm.sum += seconds
+	cm.count++
+}
+
+// TrackClient adds the connection pools of c to the metrics until c is closed.
+func (m *PrometheusMetrics) TrackClient(c *Client) {
+	m.mutex.Lock()
+	m.clients = append(m.clients, c)
+	m.mutex.Unlock()
+}
+
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	m.mutex.Lock()
+	keys := make([]callKey, 0, len(m.calls))
+	for key := range m.calls {
+		keys = append(keys, key)
+	}
+	sortCallKeys(keys)
+
+	b.WriteString("# HELP tars_client_requests_total Calls made by TARS clients by result code.\n")
+	b.WriteString("# TYPE tars_client_requests_total counter\n")
+	for _, key := range keys {
+		cm := m.calls[key]
+		codes := make([]int, 0, len(cm.codes))
+		for code := range cm.codes {
+			codes = append(codes, int(code))
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&b, "tars_client_requests_total{%s,code=\"%d\"} %d\n", key.labels(), code, cm.codes[int32(code)])
+		}
+	}
+	b.WriteString("# HELP tars_client_timeouts_total Calls of TARS clients that timed out.\n")
+	b.WriteString("# TYPE tars_client_timeouts_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "tars_client_timeouts_total{%s} %d\n", key.labels(), m.calls[key].timeouts)
+	}
+	b.WriteString("# HELP tars_client_request_duration_seconds Latency of the calls of TARS clients.\n")
+	b.WriteString("# TYPE tars_client_request_duration_seconds histogram\n")
+	for _, key := range keys {
+		cm := m.calls[key]
+		for i, bound := range m.Buckets {
+			fmt.Fprintf(&b, "tars_client_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", key.labels(), formatFloat(bound), cm.buckets[i])
+		}
+		fmt.Fprintf(&b, "tars_client_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), cm.count)
+		fmt.Fprintf(&b, "tars_client_request_duration_seconds_sum{%s} %s\n", key.labels(), formatFloat(cm.sum))
+		fmt.Fprintf(&b, "tars_client_request_duration_seconds_count{%s} %d\n", key.labels(), cm.count)
+	}
+
+	keys = keys[:0]
+	for key := range m.inFlight {
+		keys = append(keys, key)
+	}
+	sortCallKeys(keys)
+	b.WriteString("# HELP tars_client_in_flight Calls of TARS clients waiting for their response.\n")
+	b.WriteString("# TYPE tars_client_in_flight gauge\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "tars_client_in_flight{servant=\"%s\",func=\"%s\"} %d\n", escapeLabel(key.servant), escapeLabel(key.funcName), m.inFlight[key])
+	}
+
+	clients := m.clients[:0]
+	for _, c := range m.clients {
+		if !c.isClosed() {
+			clients = append(clients, c)
+		}
+	}
+	m.clients = clients
+	clients = append([]*Client(nil), clients...)
+	m.mutex.Unlock()
+
+	var pools []string
+	for _, c := range clients {
+		for _, st := range c.PoolStats() {
+			labels := fmt.Sprintf("servant=\"%s\",endpoint=\"%s\"", escapeLabel(c.servant), escapeLabel(st.Endpoint.String()))
+			pools = append(pools,
+				fmt.Sprintf("tars_client_pool_connections{%s,state=\"connected\"} %d\n", labels, st.Connected),
+				fmt.Sprintf("tars_client_pool_connections{%s,state=\"dialing\"} %d\n", labels, st.Dialing),
+				fmt.Sprintf("tars_client_pool_size{%s} %d\n", labels, st.Size),
+				fmt.Sprintf("tars_client_pool_in_flight{%s} %d\n", labels, st.InFlight),
+				fmt.Sprintf("tars_client_pool_dial_failures{%s} %d\n", labels, st.Failures))
+		}
+	}
+	for _, metric := range []struct{ name, help string }{
+		{"tars_client_pool_connections", "Connections of TARS clients by state."},
+		{"tars_client_pool_size", "Connections TARS clients keep to each endpoint."},
+		{"tars_client_pool_in_flight", "Calls waiting for their response on each endpoint."},
+		{"tars_client_pool_dial_failures", "Consecutive failed dials to each endpoint."},
+	} {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+		for _, line := range pools {
+			if strings.HasPrefix(line, metric.name+"{") {
+				b.WriteString(line)
+			}
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (key callKey) labels() string {
+	return fmt.Sprintf("servant=\"%s\",func=\"%s\",endpoint=\"%s\"", escapeLabel(key.servant), escapeLabel(key.funcName), escapeLabel(key.endpoint))
+}
+
+func sortCallKeys(keys []callKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].servant != keys[j].servant {
+			return keys[i].servant < keys[j].servant
+		}
+		if keys[i].funcName != keys[j].funcName {
+			return keys[i].funcName < keys[j].funcName
+		}
+		return keys[i].endpoint < keys[j].endpoint
+	})
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,80 @@
+package tarsgo
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(30*time.Millisecond))
+	_, endpoints, _ := ParseProxy(obj)
+	m := NewPrometheusMetrics()
+	c := NewClient(obj, time.Second, WithMetrics(m), WithMaxConn(2))
+	defer c.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+			t.Fatal(err)
+		}
+	}
+	short := NewClient(obj, 10*time.Millisecond, WithMetrics(m))
+	defer short.Close()
+	if _, err := short.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); err != ErrTarsRPCTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	server := httptest.NewServer(m)
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+	labels := `servant="Test.EchoServer.EchoObj",func="echo",endpoint="` + endpoints[0].String() + `"`
+	for _, line := range []string{
+		"# TYPE tars_client_requests_total counter",
+		"tars_client_requests_total{" + labels + `,code="-7"} 1`,
+		"tars_client_requests_total{" + labels + `,code="0"} 3`,
+		"tars_client_timeouts_total{" + labels + "} 1",
+		"tars_client_request_duration_seconds_bucket{" + labels + `,le="0.025"} 1`,
+		"tars_client_request_duration_seconds_bucket{" + labels + `,le="0.05"} 4`,
+		"tars_client_request_duration_seconds_bucket{" + labels + `,le="+Inf"} 4`,
+		"tars_client_request_duration_seconds_count{" + labels + "} 4",
+		`tars_client_in_flight{servant="Test.EchoServer.EchoObj",func="echo"} 0`,
+		`tars_client_pool_size{servant="Test.EchoServer.EchoObj",endpoint="` + endpoints[0].String() + `"} 2`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("missing %q in\n%s", line, text)
+		}
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+
+	c.Close()
+	short.Close()
+	var b strings.Builder
+	m.WriteTo(&b)
+	if strings.Contains(b.String(), "tars_client_pool_size{") {
+		t.Fatalf("closed client still reported:\n%s", b.String())
+	}
+	if escapeLabel("a\"b\\c\nd") != `a\"b\\c\nd` {
+		t.Fatal("label not escaped")
+	}
+}
+
+func TestPrometheusMetricsZeroValue(t *testing.T) {
+	var m PrometheusMetrics
+	m.CallStarted("Test.EchoServer.EchoObj", "echo")
+	m.CallFinished("Test.EchoServer.EchoObj", "echo", EndpointF{}, 0, time.Millisecond)
+	var b strings.Builder
+	m.WriteTo(&b)
+	if !strings.Contains(b.String(), `func="echo",endpoint="",le="0.005"} 1`+"\n") {
+		t.Fatalf("call not reported:\n%s", b.String())
+	}
+}
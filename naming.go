@@ -1,11 +1,179 @@
 package tarsgo
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
 	"time"
 )
 
+var ErrServantNotFound = errors.New("Servant not found")
+
+// Resolver looks up the endpoints of a servant and reports later changes.
+type Resolver interface {
+	Resolve(servant string) ([]EndpointF, error)
+	// Watch calls notify each time the endpoints of servant change, until stop is called.
+	Watch(servant string, notify func([]EndpointF)) (stop func(), err error)
+}
+
+// DefaultResolver is used by clients created without WithResolver.
+var DefaultResolver Resolver
+
+// Deprecated: use DefaultResolver or WithResolver instead.
 var DefaultNamingService *QueryFProxy
 
-func NewDefaultNaming(obj string, timeout time.Duration) {
-	DefaultNamingService = NewQueryFProxy(obj, timeout)
+// QueryFFuncs lists the registry lookups, which are all read only and thus idempotent.
+var QueryFFuncs = []string{"findObjectById", "findObjectById4Any", "findObjectById4All",
+	"findObjectByIdInSameGroup", "findObjectByIdInSameStation", "findObjectByIdInSameSet"}
+
+// NewDefaultNaming resolves servants through the registry obj. Its lookups are
+// retried and, with WithHedging in opts, hedged.
+func NewDefaultNaming(obj string, timeout time.Duration, opts ...ClientOption) {
+	DefaultNamingService = newNamingProxy(obj, timeout, opts...)
+	DefaultResolver = NewRegistryResolver(DefaultNamingService)
+}
+
+// newNamingProxy returns a QueryF proxy whose lookups are retried as idempotent.
+func newNamingProxy(obj string, timeout time.Duration, opts ...ClientOption) *QueryFProxy {
+	opts = append([]ClientOption{WithIdempotent(QueryFFuncs...)}, opts...)
+	return NewQueryFProxy(obj, timeout, opts...)
+}
+
+const defaultRefreshInterval = 60 * time.Second
+
+// RegistryResolver resolves servants through the TARS registry QueryObj.
+type RegistryResolver struct {
+	Query           QueryF
+	RefreshInterval time.Duration
+	// SetId "name.area.group" limits the endpoints to those of a set, where a group
+	// of "*" selects the whole area. All endpoints are used when it is empty.
+	SetId string
+}
+
+func NewRegistryResolver(query QueryF) *RegistryResolver {
+	return &RegistryResolver{Query: query, RefreshInterval: defaultRefreshInterval}
+}
+
+func (r *RegistryResolver) Resolve(servant string) ([]EndpointF, error) {
+	if r.SetId == "" {
+		endpoints, _, err := r.Query.FindObjectById(servant, nil)
+		return endpoints, err
+	}
+	var active, inactive []EndpointF
+	ret, _, err := r.Query.FindObjectByIdInSameSet(servant, r.SetId, &active, &inactive, nil)
+	if nil != err {
+		return nil, err
+	}
+	if ret != 0 {
+		return nil, fmt.Errorf("%w:%s in set %s", ErrServantNotFound, servant, r.SetId)
+	}
+	return active, nil
+}
+
+func (r *RegistryResolver) Watch(servant string, notify func([]EndpointF)) (func(), error) {
+	// the client resolved servant just before, so the first poll is not done right away
+	return pollEndpoints(r, servant, r.RefreshInterval, nil, notify), nil
+}
+
+// StaticResolver maps servant names to a fixed list of endpoints.
+type StaticResolver map[string][]EndpointF
+
+func (r StaticResolver) Resolve(servant string) ([]EndpointF, error) {
+	endpoints, exist := r[servant]
+	if !exist {
+		return nil, fmt.Errorf("%w:%s", ErrServantNotFound, servant)
+	}
+	return endpoints, nil
+}
+
+func (r StaticResolver) Watch(servant string, notify func([]EndpointF)) (func(), error) {
+	return func() {}, nil
+}
+
+// MemoryResolver keeps endpoints in memory and notifies watchers on Set, mostly for tests.
+type MemoryResolver struct {
+	mutex     sync.Mutex
+	endpoints map[string][]EndpointF
+	watchers  map[string]map[int]func([]EndpointF)
+	watcherID int
+}
+
+func NewMemoryResolver() *MemoryResolver {
+	return &MemoryResolver{
+		endpoints: make(map[string][]EndpointF),
+		watchers:  make(map[string]map[int]func([]EndpointF)),
+	}
+}
+
+func (r *MemoryResolver) Set(servant string, endpoints ...EndpointF) {
+	r.mutex.Lock()
+	r.endpoints[servant] = endpoints
+	notifies := make([]func([]EndpointF), 0, len(r.watchers[servant]))
+	for _, notify := range r.watchers[servant] {
+		notifies = append(notifies, notify)
+	}
+	r.mutex.Unlock()
+	for _, notify := range notifies {
+		notify(endpoints)
+	}
+}
+
+func (r *MemoryResolver) Resolve(servant string) ([]EndpointF, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	endpoints, exist := r.endpoints[servant]
+	if !exist {
+		return nil, fmt.Errorf("%w:%s", ErrServantNotFound, servant)
+	}
+	return endpoints, nil
+}
+
+func (r *MemoryResolver) Watch(servant string, notify func([]EndpointF)) (func(), error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.watcherID++
+	id := r.watcherID
+	if nil == r.watchers[servant] {
+		r.watchers[servant] = make(map[int]func([]EndpointF))
+	}
+	r.watchers[servant][id] = notify
+	return func() {
+		r.mutex.Lock()
+		delete(r.watchers[servant], id)
+		r.mutex.Unlock()
+	}, nil
+}
+
+// pollEndpoints resolves servant every interval and calls notify when the result differs
+// from last.
+func pollEndpoints(r Resolver, servant string, interval time.Duration, last []EndpointF, notify func([]EndpointF)) func() {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			endpoints, err := r.Resolve(servant)
+			if nil != err {
+				logMsg(LogLevelError, "Failed to resolve servant", Field{FieldServant, servant}, Field{FieldError, err})
+				continue
+			}
+			if !reflect.DeepEqual(endpoints, last) {
+				last = endpoints
+				notify(endpoints)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
 }
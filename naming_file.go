@@ -0,0 +1,162 @@
+package tarsgo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileResolver reads servant endpoints from a JSON or YAML file and reloads it when it changes.
+//
+// JSON files map servant names to lists of endpoint strings or EndpointF objects:
+//
+//	{"Test.HelloServer.HelloObj": ["tcp -h 127.0.0.1 -p 10001", {"host": "127.0.0.1", "port": 10002, "istcp": 1}]}
+//
+// Files ending in .yaml or .yml use the equivalent block list form:
+//
+//	Test.HelloServer.HelloObj:
+//	  - tcp -h 127.0.0.1 -p 10001
+type FileResolver struct {
+	Path         string
+	PollInterval time.Duration
+
+	mutex     sync.Mutex
+	modTime   time.Time
+	size      int64
+	endpoints map[string][]EndpointF
+}
+
+func NewFileResolver(path string) (*FileResolver, error) {
+	r := &FileResolver{Path: path, PollInterval: 5 * time.Second}
+	if err := r.reload(); nil != err {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *FileResolver) Resolve(servant string) ([]EndpointF, error) {
+	if err := r.reload(); nil != err {
+		return nil, err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	endpoints, exist := r.endpoints[servant]
+	if !exist {
+		return nil, fmt.Errorf("%w:%s", ErrServantNotFound, servant)
+	}
+	return endpoints, nil
+}
+
+func (r *FileResolver) Watch(servant string, notify func([]EndpointF)) (func(), error) {
+	last, _ := r.Resolve(servant)
+	return pollEndpoints(r, servant, r.PollInterval, last, notify), nil
+}
+
+// reload parses the file again if its size or modification time changed.
+func (r *FileResolver) reload() error {
+	fi, err := os.Stat(r.Path)
+	if nil != err {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if nil != r.endpoints && fi.ModTime().Equal(r.modTime) && fi.Size() == r.size {
+		return nil
+	}
+	data, err := os.ReadFile(r.Path)
+	if nil != err {
+		return err
+	}
+	var endpoints map[string][]EndpointF
+	switch strings.ToLower(filepath.Ext(r.Path)) {
+	case ".yaml", ".yml":
+		endpoints, err = parseEndpointsYAML(data)
+	default:
+		endpoints, err = parseEndpointsJSON(data)
+	}
+	if nil != err {
+		return fmt.Errorf("Invalid endpoints file %s:%v", r.Path, err)
+	}
+	r.endpoints = endpoints
+	r.modTime = fi.ModTime()
+	r.size = fi.Size()
+	return nil
+}
+
+func parseEndpointsJSON(data []byte) (map[string][]EndpointF, error) {
+	var raw map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &raw); nil != err {
+		return nil, err
+	}
+	servants := make(map[string][]EndpointF, len(raw))
+	for servant, items := range raw {
+		endpoints := make([]EndpointF, 0, len(items))
+		for _, item := range items {
+			var s string
+			if nil == json.Unmarshal(item, &s) {
+				e, err := ParseEndpoint(s)
+				if nil != err {
+					return nil, err
+				}
+				endpoints = append(endpoints, e)
+				continue
+			}
+			var e EndpointF
+			if err := json.Unmarshal(item, &e); nil != err {
+				return nil, err
+			}
+			endpoints = append(endpoints, e)
+		}
+		servants[servant] = endpoints
+	}
+	return servants, nil
+}
+
+// parseEndpointsYAML understands the subset of YAML needed for a map of string lists.
+func parseEndpointsYAML(data []byte) (map[string][]EndpointF, error) {
+	servants := make(map[string][]EndpointF)
+	servant := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "-") {
+			if servant == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a servant", lineno)
+			}
+			e, err := ParseEndpoint(unquoteYAML(strings.TrimSpace(trimmed[1:])))
+			if nil != err {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			servants[servant] = append(servants[servant], e)
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' || !strings.HasSuffix(trimmed, ":") {
+			return nil, fmt.Errorf("line %d: expected 'servant:' or '- endpoint'", lineno)
+		}
+		servant = unquoteYAML(strings.TrimSpace(strings.TrimSuffix(trimmed, ":")))
+		if _, exist := servants[servant]; !exist {
+			servants[servant] = []EndpointF{}
+		}
+	}
+	return servants, scanner.Err()
+}
+
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
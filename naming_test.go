@@ -0,0 +1,74 @@
+package tarsgo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileResolver(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "endpoints.yaml")
+	yaml := "# local servants\nTest.HelloServer.HelloObj:\n  - tcp -h 127.0.0.1 -p 10001\n  - \"tcp -h 127.0.0.1 -p 10002\"\n"
+	if err := os.WriteFile(path, []byte(yaml), 0644); nil != err {
+		t.Fatal(err)
+	}
+	r, err := NewFileResolver(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	r.PollInterval = 10 * time.Millisecond
+	endpoints, err := r.Resolve("Test.HelloServer.HelloObj")
+	if nil != err || len(endpoints) != 2 || endpoints[1].Port != 10002 {
+		t.Fatalf("unexpected endpoints %v, %v", endpoints, err)
+	}
+	if _, err = r.Resolve("Test.Missing.Obj"); nil == err {
+		t.Fatal("expected error for missing servant")
+	}
+
+	changed := make(chan []EndpointF, 1)
+	stop, _ := r.Watch("Test.HelloServer.HelloObj", func(endpoints []EndpointF) {
+		changed <- endpoints
+	})
+	defer stop()
+	yaml = "Test.HelloServer.HelloObj:\n  - tcp -h 127.0.0.2 -p 10003\n"
+	if err := os.WriteFile(path, []byte(yaml), 0644); nil != err {
+		t.Fatal(err)
+	}
+	select {
+	case endpoints = <-changed:
+		if len(endpoints) != 1 || endpoints[0].Host != "127.0.0.2" {
+			t.Fatalf("unexpected endpoints %v", endpoints)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watch was not notified")
+	}
+
+	path = filepath.Join(dir, "endpoints.json")
+	json := `{"Test.HelloServer.HelloObj": ["udp -h 127.0.0.1 -p 10001", {"host": "127.0.0.2", "port": 10003, "istcp": 1}]}`
+	if err := os.WriteFile(path, []byte(json), 0644); nil != err {
+		t.Fatal(err)
+	}
+	r, err = NewFileResolver(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	endpoints, err = r.Resolve("Test.HelloServer.HelloObj")
+	if nil != err || len(endpoints) != 2 || endpoints[0].Istcp != EndpointUDP || endpoints[1].Host != "127.0.0.2" {
+		t.Fatalf("unexpected endpoints %v, %v", endpoints, err)
+	}
+}
+
+func TestClientResolver(t *testing.T) {
+	r := NewMemoryResolver()
+	r.Set("Test.HelloServer.HelloObj", EndpointF{Host: "127.0.0.1", Port: 10001, Istcp: 1})
+	c := NewClient("Test.HelloServer.HelloObj", time.Second, WithResolver(r))
+	if endpoints := c.getEndpoints(); len(endpoints) != 1 || endpoints[0].Port != 10001 {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+	r.Set("Test.HelloServer.HelloObj", EndpointF{Host: "127.0.0.1", Port: 10002, Istcp: 1})
+	if endpoints := c.getEndpoints(); len(endpoints) != 1 || endpoints[0].Port != 10002 {
+		t.Fatalf("endpoint change not applied, got %v", endpoints)
+	}
+}
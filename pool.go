@@ -0,0 +1,562 @@
+package tarsgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultMaxConn = 5
+
+var ErrConnectionClosed = errors.New("Tars rpc connection closed")
+
+// PoolConfig tunes the connections a Client keeps to each endpoint. The number of
+// connections per endpoint is Client.MaxConn.
+type PoolConfig struct {
+	// IdleTimeout closes connections without traffic for this long; they are dialed again on demand.
+	IdleTimeout time.Duration
+	// ProbeInterval is how long a connection may go without reading anything before it is
+	// probed with tars_ping; connections failing the probe are closed and redialed.
+	ProbeInterval time.Duration
+	// MinBackoff and MaxBackoff bound the jittered exponential delay between reconnect attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// DialTimeout bounds a single connection attempt, no limit when zero.
+	DialTimeout time.Duration
+}
+
+var DefaultPoolConfig = PoolConfig{
+	IdleTimeout:   10 * time.Minute,
+	ProbeInterval: 30 * time.Second,
+	MinBackoff:    100 * time.Millisecond,
+	MaxBackoff:    30 * time.Second,
+	DialTimeout:   3 * time.Second,
+}
+
+// WithPoolConfig replaces DefaultPoolConfig for one client.
+func WithPoolConfig(cfg PoolConfig) ClientOption {
+	return func(c *Client) {
+		c.poolConfig = cfg
+	}
+}
+
+// WithMaxConn sets Client.MaxConn.
+func WithMaxConn(n int) ClientOption {
+	return func(c *Client) {
+		c.MaxConn = n
+	}
+}
+
+// PoolStats is a snapshot of the connections to one endpoint.
+type PoolStats struct {
+	Endpoint  EndpointF
+	Size      int
+	Connected int
+	Dialing   int
+	InFlight  int64
+	// Failures counts consecutive failed dials, LastError is the latest of them.
+	Failures  int
+	LastError error
+}
+
+type rpcChannel struct {
+	Conn      net.Conn
+	pool      *connPool
+	slot      int
+	ch        chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	// pending, lastActive and lastRead are accessed atomically.
+	pending    int64
+	lastActive int64
+	lastRead   int64
+	probing    int32
+}
+
+func (rc *rpcChannel) send(frame []byte, timeout <-chan time.Time) error {
+	select {
+	case rc.ch <- frame:
+		return nil
+	case <-rc.done:
+		return ErrConnectionClosed
+	case <-timeout:
+		return ErrTarsRPCTimeout
+	}
+}
+
+// close shuts the connection down once; redial asks the pool to replace it.
+func (rc *rpcChannel) close(err error, redial bool) {
+	rc.closeOnce.Do(func() {
+		close(rc.done)
+		rc.Conn.Close()
+		rc.pool.detach(rc, redial)
+		if nil != err {
+			rc.pool.client.log(LogLevelWarn, "RPCChannel closed", Field{FieldEndpoint, rc.pool.endpoint.String()}, Field{"slot", rc.slot}, Field{FieldError, err})
+		}
+	})
+}
+
+// touch records caller traffic on rc. Probes do not count, so a probed connection can
+// still be reaped as idle.
+func (rc *rpcChannel) touch() {
+	atomic.StoreInt64(&rc.lastActive, time.Now().UnixNano())
+}
+
+func (rc *rpcChannel) read() {
+	c := rc.pool.client
+	bufReader := bufio.NewReader(rc.Conn)
+	var datagram []byte
+	if rc.pool.endpoint.Istcp == EndpointUDP {
+		datagram = make([]byte, maxUDPPacketSize)
+	}
+	var err error
+	for {
+		var b []byte
+		if nil != datagram {
+			var n int
+			n, err = rc.Conn.Read(datagram)
+			if nil != err {
+				break
+			}
+			b, err = unpackDatagram(datagram[:n])
+			if nil != err {
+				rc.pool.client.log(LogLevelError, "Invalid datagram", Field{FieldEndpoint, rc.pool.endpoint.String()}, Field{FieldError, err})
+				continue
+			}
+		} else {
+			b, err = readPacket(bufReader)
+			if nil != err {
+				break
+			}
+		}
+		atomic.StoreInt64(&rc.lastRead, time.Now().UnixNano())
+		var resp ResponsePacket
+		err = resp.Decode(bytes.NewBuffer(b))
+		if nil != err {
+			rc.pool.client.log(LogLevelError, "Failed to decode 'ResponsePacket'", Field{FieldEndpoint, rc.pool.endpoint.String()}, Field{FieldError, err})
+			continue
+		}
+		s := c.getRPCSession(resp.IRequestId)
+		if nil == s {
+			if c.dropAbandoned(resp.IRequestId) {
+				continue
+			}
+			rc.pool.client.log(LogLevelWarn, "Missing session, maybe deleted by timeout task", Field{FieldEndpoint, rc.pool.endpoint.String()}, Field{FieldRequestID, resp.IRequestId})
+			continue
+		}
+		select {
+		case s.ch <- &resp:
+		default:
+		}
+	}
+	select {
+	case <-rc.done:
+		// closed locally, the read error is expected
+		rc.close(nil, false)
+	default:
+		rc.close(fmt.Errorf("read failed:%v", err), true)
+	}
+}
+
+func (rc *rpcChannel) write() {
+	for {
+		select {
+		case frame := <-rc.ch:
+			_, err := rc.Conn.Write(frame)
+			if nil != err {
+				rc.close(fmt.Errorf("write failed:%v", err), true)
+				return
+			}
+		case <-rc.done:
+			return
+		}
+	}
+}
+
+// connPool keeps up to size connections to one endpoint. Empty slots are dialed in the
+// background, so callers never wait on a dial.
+type connPool struct {
+	client   *Client
+	endpoint EndpointF
+	size     int
+	config   PoolConfig
+
+	mutex    sync.Mutex
+	conns    []*rpcChannel
+	dialing  []bool
+	failures int
+	lastErr  error
+	closed   bool
+	retired  bool
+	done     chan struct{}
+	// ctx is cancelled on close so that pending dials give up.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newConnPool(c *Client, e EndpointF, size int, config PoolConfig) *connPool {
+	p := &connPool{
+		client:   c,
+		endpoint: e,
+		size:     size,
+		config:   config,
+		conns:    make([]*rpcChannel, size),
+		dialing:  make([]bool, size),
+		done:     make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	c.goroutine(p.maintain)
+	return p
+}
+
+// get returns the least loaded connection, or nil if none is connected yet. It starts
+// dialing an empty slot when nothing is connected or every connection is busy.
+func (p *connPool) get() *rpcChannel {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed || p.retired {
+		return nil
+	}
+	var best *rpcChannel
+	for _, rc := range p.conns {
+		if nil != rc && (nil == best || atomic.LoadInt64(&rc.pending) < atomic.LoadInt64(&best.pending)) {
+			best = rc
+		}
+	}
+	if nil == best || atomic.LoadInt64(&best.pending) > 0 {
+		for i, rc := range p.conns {
+			if nil == rc && !p.dialing[i] {
+				p.dialing[i] = true
+				slot := i
+				p.client.goroutine(func() { p.dial(slot) })
+				break
+			}
+		}
+	}
+	return best
+}
+
+// dialOnce connects to the endpoint, giving up after DialTimeout or when the pool is closed.
+func (p *connPool) dialOnce() (net.Conn, error) {
+	ctx := p.ctx
+	if p.config.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.DialTimeout)
+		defer cancel()
+	}
+	return dialEndpoint(ctx, p.endpoint, p.client.tlsConfig)
+}
+
+// dial connects a slot, retrying with backoff until it succeeds or the pool is closed.
+func (p *connPool) dial(slot int) {
+	for attempt := 0; ; attempt++ {
+		conn, err := p.dialOnce()
+		p.mutex.Lock()
+		if p.closed {
+			p.dialing[slot] = false
+			p.mutex.Unlock()
+			if nil == err {
+				conn.Close()
+			}
+			return
+		}
+		if nil == err {
+			rc := &rpcChannel{
+				Conn: conn,
+				pool: p,
+				slot: slot,
+				ch:   make(chan []byte, 100),
+				done: make(chan struct{}),
+			}
+			rc.touch()
+			rc.lastRead = rc.lastActive
+			p.conns[slot] = rc
+			p.dialing[slot] = false
+			p.failures = 0
+			p.lastErr = nil
+			p.client.goroutine(rc.write)
+			p.client.goroutine(rc.read)
+			p.mutex.Unlock()
+			p.client.notifyConnReady()
+			return
+		}
+		p.failures++
+		p.lastErr = err
+		p.mutex.Unlock()
+		p.client.log(LogLevelError, "Failed to connect server", Field{FieldEndpoint, p.endpoint.String()}, Field{FieldError, err})
+		select {
+		case <-time.After(jitterBackoff(p.config.MinBackoff, p.config.MaxBackoff, attempt)):
+		case <-p.done:
+			p.mutex.Lock()
+			p.dialing[slot] = false
+			p.mutex.Unlock()
+			return
+		}
+	}
+}
+
+func (p *connPool) detach(rc *rpcChannel, redial bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conns[rc.slot] == rc {
+		p.conns[rc.slot] = nil
+	}
+	if redial && !p.closed && !p.dialing[rc.slot] {
+		p.dialing[rc.slot] = true
+		p.client.goroutine(func() { p.dial(rc.slot) })
+	}
+}
+
+// maintain reaps idle connections and probes silent ones.
+func (p *connPool) maintain() {
+	interval := p.config.ProbeInterval
+	if p.config.IdleTimeout > 0 && (interval <= 0 || p.config.IdleTimeout < interval) {
+		interval = p.config.IdleTimeout
+	}
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		now := time.Now().UnixNano()
+		p.mutex.Lock()
+		conns := make([]*rpcChannel, 0, len(p.conns))
+		for _, rc := range p.conns {
+			if nil != rc {
+				conns = append(conns, rc)
+			}
+		}
+		p.mutex.Unlock()
+		for _, rc := range conns {
+			idle := time.Duration(now - atomic.LoadInt64(&rc.lastActive))
+			silent := time.Duration(now - atomic.LoadInt64(&rc.lastRead))
+			if p.config.IdleTimeout > 0 && idle > p.config.IdleTimeout && atomic.LoadInt64(&rc.pending) == 0 {
+				rc.close(nil, false)
+			} else if p.config.ProbeInterval > 0 && silent > p.config.ProbeInterval && atomic.CompareAndSwapInt32(&rc.probing, 0, 1) {
+				probed := rc
+				p.client.goroutine(func() { p.probe(probed) })
+			}
+		}
+	}
+}
+
+// probeFuncName is the function liveness probes call.
+const probeFuncName = "tars_ping"
+
+// probe sends tars_ping on rc; any response, even an error code, proves the peer alive.
+func (p *connPool) probe(rc *rpcChannel) {
+	defer atomic.StoreInt32(&rc.probing, 0)
+	c := p.client
+	packet := &RequestPacket{
+		IVersion:     1,
+		SServantName: c.servant,
+		SFuncName:    probeFuncName,
+		IRequestId:   c.nextRequestID(),
+		ITimeout:     int32(c.Timeout / time.Millisecond),
+	}
+	_, err := c.call(rc, packet, time.Now().Add(c.Timeout), nil)
+	if nil != err {
+		rc.close(fmt.Errorf("liveness probe failed:%v", err), true)
+	}
+}
+
+func (p *connPool) inFlight() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var n int64
+	for _, rc := range p.conns {
+		if nil != rc {
+			n += atomic.LoadInt64(&rc.pending)
+		}
+	}
+	return n
+}
+
+// retire stops handing out connections and closes the pool once the calls on it have
+// returned, or after the client timeout at the latest.
+func (p *connPool) retire() {
+	p.mutex.Lock()
+	p.retired = true
+	p.mutex.Unlock()
+	deadline := time.Now().Add(p.client.Timeout)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for p.inFlight() > 0 && time.Now().Before(deadline) {
+		select {
+		case <-ticker.C:
+		case <-p.done:
+			return
+		}
+	}
+	p.close()
+}
+
+func (p *connPool) close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.cancel()
+	conns := make([]*rpcChannel, 0, len(p.conns))
+	for _, rc := range p.conns {
+		if nil != rc {
+			conns = append(conns, rc)
+		}
+	}
+	p.mutex.Unlock()
+	for _, rc := range conns {
+		rc.close(nil, false)
+	}
+}
+
+func (p *connPool) stats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	st := PoolStats{
+		Endpoint:  p.endpoint,
+		Size:      p.size,
+		Failures:  p.failures,
+		LastError: p.lastErr,
+	}
+	for i, rc := range p.conns {
+		if nil != rc {
+			st.Connected++
+			st.InFlight += atomic.LoadInt64(&rc.pending)
+		}
+		if p.dialing[i] {
+			st.Dialing++
+		}
+	}
+	return st
+}
+
+func endpointKey(e EndpointF) string {
+	return e.Scheme() + " " + e.Address()
+}
+
+// getPools returns the pools in endpoint order, creating them on first use, and the channel
+// closed by the next notifyConnReady.
+func (c *Client) getPools() ([]*connPool, chan struct{}) {
+	c.poolsMutex.Lock()
+	defer c.poolsMutex.Unlock()
+	if nil == c.pools && !c.closed {
+		c.pools = make(map[string]*connPool)
+		c.syncPoolsLocked()
+	}
+	return c.poolList, c.connReady
+}
+
+// syncPoolsLocked creates pools for new endpoints and retires those of removed ones.
+func (c *Client) syncPoolsLocked() {
+	size := c.MaxConn
+	if size <= 0 {
+		size = defaultMaxConn
+	}
+	pools := make(map[string]*connPool, len(c.endpoints))
+	list := make([]*connPool, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		key := endpointKey(e)
+		if _, exist := pools[key]; exist {
+			continue
+		}
+		p, exist := c.pools[key]
+		if !exist {
+			p = newConnPool(c, e, size, c.poolConfig)
+		}
+		pools[key] = p
+		list = append(list, p)
+	}
+	for key, p := range c.pools {
+		if _, exist := pools[key]; !exist {
+			c.goroutine(p.retire)
+		}
+	}
+	c.pools = pools
+	c.poolList = list
+	// wake callers waiting on the previous pools
+	close(c.connReady)
+	c.connReady = make(chan struct{})
+}
+
+func (c *Client) notifyConnReady() {
+	c.poolsMutex.Lock()
+	close(c.connReady)
+	c.connReady = make(chan struct{})
+	c.poolsMutex.Unlock()
+}
+
+// pickRPCChannel returns a connected channel of pools round robin, or nil if there is none.
+// Pools in avoid are skipped unless all of them are.
+func (c *Client) pickRPCChannel(pools []*connPool, avoid map[*connPool]bool) *rpcChannel {
+	skip := false
+	for _, p := range pools {
+		if !avoid[p] {
+			skip = len(avoid) > 0
+			break
+		}
+	}
+	start := int(atomic.AddUint32(&c.poolCursor, 1))
+	for i := range pools {
+		p := pools[(start+i)%len(pools)]
+		if skip && avoid[p] {
+			continue
+		}
+		if rc := p.get(); nil != rc {
+			return rc
+		}
+	}
+	return nil
+}
+
+// getRPCChannel picks a connected channel, waiting for a background dial to finish when
+// none is connected.
+func (c *Client) getRPCChannel(deadline time.Time, avoid map[*connPool]bool) (*rpcChannel, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.done:
+			return nil, ErrClientClosed
+		default:
+		}
+		pools, ready := c.getPools()
+		if len(pools) == 0 {
+			return nil, ErrNoEndpoint
+		}
+		if rc := c.pickRPCChannel(pools, avoid); nil != rc {
+			return rc, nil
+		}
+		select {
+		case <-ready:
+		case <-timer.C:
+			return nil, ErrNoRPCChannel
+		case <-c.done:
+			return nil, ErrClientClosed
+		}
+	}
+}
+
+// PoolStats reports the state of the connections to every endpoint.
+func (c *Client) PoolStats() []PoolStats {
+	pools, _ := c.getPools()
+	stats := make([]PoolStats, len(pools))
+	for i, p := range pools {
+		stats[i] = p.stats()
+	}
+	return stats
+}
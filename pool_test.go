@@ -0,0 +1,138 @@
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowDispatcher echoes after a delay so that calls pile up on the connections.
+type slowDispatcher time.Duration
+
+func (d slowDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	time.Sleep(time.Duration(d))
+	resp.SBuffer = req.SBuffer
+	return nil
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPoolMaxConn(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(20*time.Millisecond))
+	c := NewClient(obj, time.Second, WithMaxConn(3))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	stats := c.PoolStats()
+	if len(stats) != 1 || stats[0].Size != 3 || stats[0].Connected == 0 || stats[0].Connected > 3 {
+		t.Fatalf("unexpected pool stats %+v", stats)
+	}
+}
+
+func TestPoolReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	s := NewServer()
+	s.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	go s.Serve(l)
+
+	obj := fmt.Sprintf("Test.EchoServer.EchoObj@tcp -h 127.0.0.1 -p %d", l.Addr().(*net.TCPAddr).Port)
+	c := NewClient(obj, time.Second, WithMaxConn(1), WithPoolConfig(PoolConfig{
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	}))
+	if _, err = c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+		t.Fatal(err)
+	}
+	s.Close()
+	waitFor(t, "failed redials", func() bool { return c.PoolStats()[0].Failures > 0 })
+
+	l, err = net.Listen("tcp", addr)
+	if nil != err {
+		t.Skipf("cannot listen on %s again: %v", addr, err)
+	}
+	s = NewServer()
+	s.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	go s.Serve(l)
+	defer s.Close()
+	waitFor(t, "reconnect", func() bool { return c.PoolStats()[0].Connected == 1 })
+	if _, err = c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolIdleAndProbe(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", echoDispatcher{})
+	c := NewClient(obj, time.Second, WithPoolConfig(PoolConfig{IdleTimeout: 50 * time.Millisecond}))
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+		t.Fatal(err)
+	}
+	waitFor(t, "idle reaping", func() bool { return c.PoolStats()[0].Connected == 0 })
+
+	// probes keep a silent connection checked but do not keep it from idling
+	c = NewClient(obj, time.Second, WithPoolConfig(PoolConfig{
+		IdleTimeout:   200 * time.Millisecond,
+		ProbeInterval: 50 * time.Millisecond,
+	}))
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+		t.Fatal(err)
+	}
+	waitFor(t, "idle reaping of a probed connection", func() bool { return c.PoolStats()[0].Connected == 0 })
+
+	// a peer that accepts connections but never answers fails the liveness probe
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if nil != err {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	obj = fmt.Sprintf("Test.EchoServer.EchoObj@tcp -h 127.0.0.1 -p %d", l.Addr().(*net.TCPAddr).Port)
+	c = NewClient(obj, 50*time.Millisecond, WithMaxConn(1), WithPoolConfig(PoolConfig{
+		ProbeInterval: 50 * time.Millisecond,
+		MinBackoff:    10 * time.Millisecond,
+		MaxBackoff:    10 * time.Millisecond,
+	}))
+	if _, err = c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); err != ErrTarsRPCTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case conn := <-accepted:
+			defer conn.Close()
+		case <-time.After(3 * time.Second):
+			t.Fatal("silent connection was not probed and redialed")
+		}
+	}
+}
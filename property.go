@@ -0,0 +1,278 @@
+package tarsgo
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PropertyPolicy aggregates the values reported to a property between two reports. Its
+// methods are called with the lock of the property held.
+type PropertyPolicy interface {
+	// Policy is the name of the policy as the property service knows it, such as "Sum".
+	Policy() string
+	Add(value int64)
+	// Flush returns the aggregated value and starts over.
+	Flush() string
+}
+
+type sumPolicy struct {
+	sum int64
+}
+
+// Sum reports the sum of the values.
+func Sum() PropertyPolicy {
+	return new(sumPolicy)
+}
+
+func (p *sumPolicy) Policy() string { return "Sum" }
+
+func (p *sumPolicy) Add(value int64) { p.sum += value }
+
+func (p *sumPolicy) Flush() string {
+	s := strconv.FormatInt(p.sum, 10)
+	p.sum = 0
+	return s
+}
+
+type avgPolicy struct {
+	sum, count int64
+}
+
+// Avg reports the mean of the values.
+func Avg() PropertyPolicy {
+	return new(avgPolicy)
+}
+
+func (p *avgPolicy) Policy() string { return "Avg" }
+
+func (p *avgPolicy) Add(value int64) {
+	p.sum += value
+	p.count++
+}
+
+func (p *avgPolicy) Flush() string {
+	var avg float64
+	if p.count > 0 {
+		avg = float64(p.sum) / float64(p.count)
+	}
+	p.sum, p.count = 0, 0
+	return strconv.FormatFloat(avg, 'f', 3, 64)
+}
+
+type maxPolicy struct {
+	max   int64
+	valid bool
+}
+
+// Max reports the largest value.
+func Max() PropertyPolicy {
+	return new(maxPolicy)
+}
+
+func (p *maxPolicy) Policy() string { return "Max" }
+
+func (p *maxPolicy) Add(value int64) {
+	if !p.valid || value > p.max {
+		p.max, p.valid = value, true
+	}
+}
+
+func (p *maxPolicy) Flush() string {
+	s := strconv.FormatInt(p.max, 10)
+	p.max, p.valid = 0, false
+	return s
+}
+
+type minPolicy struct {
+	min   int64
+	valid bool
+}
+
+// Min reports the smallest value.
+func Min() PropertyPolicy {
+	return new(minPolicy)
+}
+
+func (p *minPolicy) Policy() string { return "Min" }
+
+func (p *minPolicy) Add(value int64) {
+	if !p.valid || value < p.min {
+		p.min, p.valid = value, true
+	}
+}
+
+func (p *minPolicy) Flush() string {
+	s := strconv.FormatInt(p.min, 10)
+	p.min, p.valid = 0, false
+	return s
+}
+
+type countPolicy struct {
+	count int64
+}
+
+// Count reports how many values were reported.
+func Count() PropertyPolicy {
+	return new(countPolicy)
+}
+
+func (p *countPolicy) Policy() string { return "Count" }
+
+func (p *countPolicy) Add(value int64) { p.count++ }
+
+func (p *countPolicy) Flush() string {
+	s := strconv.FormatInt(p.count, 10)
+	p.count = 0
+	return s
+}
+
+type distrPolicy struct {
+	bounds []int64
+	counts []int64
+}
+
+// Distr reports how many values fall in each bucket, as "bound|count" pairs separated by
+// commas. A value is counted in the first bucket whose bound is not below it, values above
+// the last bound in the last bucket. bounds must be sorted.
+func Distr(bounds ...int64) PropertyPolicy {
+	return &distrPolicy{bounds: bounds, counts: make([]int64, len(bounds))}
+}
+
+func (p *distrPolicy) Policy() string { return "Distr" }
+
+func (p *distrPolicy) Add(value int64) {
+	if len(p.bounds) == 0 {
+		return
+	}
+	i := len(p.bounds) - 1
+	for j, bound := range p.bounds {
+		if value <= bound {
+			i = j
+			break
+		}
+	}
+	p.counts[i]++
+}
+
+func (p *distrPolicy) Flush() string {
+	pairs := make([]string, len(p.bounds))
+	for i, bound := range p.bounds {
+		pairs[i] = strconv.FormatInt(bound, 10) + "|" + strconv.FormatInt(p.counts[i], 10)
+		p.counts[i] = 0
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Property is a business metric aggregated by its policies. It is safe for concurrent use.
+type Property struct {
+	name     string
+	mutex    sync.Mutex
+	policies []PropertyPolicy
+	reported bool
+}
+
+// Report adds value to every policy of p.
+func (p *Property) Report(value int64) {
+	p.mutex.Lock()
+	for _, policy := range p.policies {
+		policy.Add(value)
+	}
+	p.reported = true
+	p.mutex.Unlock()
+}
+
+// flush returns the aggregated values of p, nil if nothing was reported since the last flush.
+func (p *Property) flush() []StatPropInfo {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.reported {
+		return nil
+	}
+	p.reported = false
+	infos := make([]StatPropInfo, len(p.policies))
+	for i, policy := range p.policies {
+		infos[i] = StatPropInfo{Policy: policy.Policy(), Value: policy.Flush()}
+	}
+	return infos
+}
+
+// PropertyReporter reports properties to the TARS property service, usually
+// tars.tarsproperty.PropertyObj.
+type PropertyReporter struct {
+	config     StatConfig
+	proxy      *PropertyFProxy
+	mutex      sync.Mutex
+	properties map[string]*Property
+	flusher    *flushLoop
+}
+
+// NewPropertyReporter reports to the property servant obj and starts reporting in the
+// background until Close. Module, MasterIp and SetDivision of cfg identify the reporting
+// server.
+func NewPropertyReporter(obj string, cfg StatConfig, opts ...ClientOption) *PropertyReporter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultStatInterval
+	}
+	r := &PropertyReporter{
+		config:     cfg,
+		proxy:      NewPropertyFProxy(obj, 3*time.Second, opts...),
+		properties: make(map[string]*Property),
+	}
+	r.flusher = startFlushLoop(cfg.Interval, r.Report)
+	return r
+}
+
+// Property returns the property called name, creating it with policies if it does not
+// exist yet. The policies of an existing property are kept.
+func (r *PropertyReporter) Property(name string, policies ...PropertyPolicy) *Property {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	p, exist := r.properties[name]
+	if !exist {
+		p = &Property{name: name, policies: policies}
+		r.properties[name] = p
+	}
+	return p
+}
+
+// Report sends the values aggregated since the last report.
+func (r *PropertyReporter) Report() {
+	r.mutex.Lock()
+	properties := make([]*Property, 0, len(r.properties))
+	for _, p := range r.properties {
+		properties = append(properties, p)
+	}
+	r.mutex.Unlock()
+
+	msg := make(map[StatPropMsgHead]StatPropMsgBody)
+	for _, p := range properties {
+		infos := p.flush()
+		if nil == infos {
+			continue
+		}
+		head := StatPropMsgHead{
+			ModuleName:   r.config.Module,
+			Ip:           r.config.MasterIp,
+			PropertyName: p.name,
+			IPropertyVer: 1,
+		}
+		if name, area, group, ok := splitSetDivision(r.config.SetDivision); ok {
+			head.SetName, head.SetArea, head.SetID = name, area, group
+		}
+		msg[head] = StatPropMsgBody{VInfo: infos}
+	}
+	if len(msg) == 0 {
+		return
+	}
+	if _, _, err := r.proxy.ReportPropMsg(msg, nil); nil != err {
+		logMsg(LogLevelError, "Failed to report properties", Field{"count", len(msg)}, Field{FieldError, err})
+	}
+}
+
+// Close reports the pending property values and stops the reporter.
+func (r *PropertyReporter) Close() error {
+	r.flusher.close(r.proxy.TarsClient.Close)
+	return nil
+}
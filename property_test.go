@@ -0,0 +1,61 @@
+package tarsgo
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// propertyRecorder is a stand-in PropertyObj keeping what was reported.
+type propertyRecorder struct {
+	mutex sync.Mutex
+	msgs  []map[StatPropMsgHead]StatPropMsgBody
+}
+
+func (p *propertyRecorder) ReportPropMsg(statmsg map[StatPropMsgHead]StatPropMsgBody, context map[string]string) (int32, map[string]string, error) {
+	p.mutex.Lock()
+	p.msgs = append(p.msgs, statmsg)
+	p.mutex.Unlock()
+	return 0, nil, nil
+}
+
+func TestPropertyReporter(t *testing.T) {
+	recorder := new(propertyRecorder)
+	_, propertyObj := startTestServer(t, "tars.tarsproperty.PropertyObj", &PropertyFDispatcher{recorder})
+
+	r := NewPropertyReporter(propertyObj, StatConfig{Module: "Test.EchoServer", MasterIp: "127.0.0.1", Interval: time.Hour})
+	orders := r.Property("orders", Sum(), Avg(), Max(), Min(), Count(), Distr(10, 100, 1000))
+	if r.Property("orders", Count()) != orders {
+		t.Fatal("property created twice")
+	}
+	r.Property("idle", Count())
+	for _, v := range []int64{5, 50, 20, 2000} {
+		orders.Report(v)
+	}
+	r.Report()
+	r.Report()
+	orders.Report(-3)
+	r.Close()
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if len(recorder.msgs) != 2 {
+		t.Fatalf("expected 2 reports, got %+v", recorder.msgs)
+	}
+	head := StatPropMsgHead{ModuleName: "Test.EchoServer", Ip: "127.0.0.1", PropertyName: "orders", IPropertyVer: 1}
+	for i, expected := range [][]StatPropInfo{
+		{{"Sum", "2075"}, {"Avg", "518.750"}, {"Max", "2000"}, {"Min", "5"}, {"Count", "4"}, {"Distr", "10|1,100|2,1000|1"}},
+		{{"Sum", "-3"}, {"Avg", "-3.000"}, {"Max", "-3"}, {"Min", "-3"}, {"Count", "1"}, {"Distr", "10|1,100|0,1000|0"}},
+	} {
+		msg := recorder.msgs[i]
+		body, exist := msg[head]
+		if len(msg) != 1 || !exist || len(body.VInfo) != len(expected) {
+			t.Fatalf("unexpected report %+v", msg)
+		}
+		for j, info := range body.VInfo {
+			if info != expected[j] {
+				t.Fatalf("report %d: expected %+v, got %+v", i, expected[j], info)
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package tarsgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RegistryEndpoint is one endpoint of an object in a RegistryConfig.
+type RegistryEndpoint struct {
+	Endpoint string `json:"endpoint"`
+	Group    string `json:"group"`
+	Station  string `json:"station"`
+	SetId    string `json:"setId"`
+	Inactive bool   `json:"inactive"`
+}
+
+// RegistryConfig describes the objects served by a Registry.
+//
+// Groups maps caller addresses, either plain IPs or CIDR blocks, to group names; it is
+// used by the group filtering lookups.
+type RegistryConfig struct {
+	Objects map[string][]RegistryEndpoint `json:"objects"`
+	Groups  map[string]string             `json:"groups"`
+}
+
+type registryEndpoint struct {
+	RegistryEndpoint
+	e EndpointF
+}
+
+// Registry is a lightweight implementation of the TARS registry QueryObj for local
+// development and integration tests.
+type Registry struct {
+	Path string
+
+	mutex   sync.RWMutex
+	objects map[string][]registryEndpoint
+	groups  map[string]string
+}
+
+var _ QueryF = (*Registry)(nil)
+
+func NewRegistry(cfg *RegistryConfig) (*Registry, error) {
+	r := &Registry{}
+	if err := r.apply(cfg); nil != err {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadRegistry creates a Registry from a JSON encoded RegistryConfig file.
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{Path: path}
+	if err := r.Reload(); nil != err {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the config file at Path again.
+func (r *Registry) Reload() error {
+	data, err := os.ReadFile(r.Path)
+	if nil != err {
+		return err
+	}
+	cfg := new(RegistryConfig)
+	if err = json.Unmarshal(data, cfg); nil != err {
+		return fmt.Errorf("Invalid registry config %s:%v", r.Path, err)
+	}
+	return r.apply(cfg)
+}
+
+func (r *Registry) apply(cfg *RegistryConfig) error {
+	objects := make(map[string][]registryEndpoint, len(cfg.Objects))
+	for obj, items := range cfg.Objects {
+		for _, item := range items {
+			e, err := ParseEndpoint(item.Endpoint)
+			if nil != err {
+				return fmt.Errorf("Invalid endpoint %q of %s:%v", item.Endpoint, obj, err)
+			}
+			e.SetId = item.SetId
+			objects[obj] = append(objects[obj], registryEndpoint{item, e})
+		}
+	}
+	r.mutex.Lock()
+	r.objects = objects
+	r.groups = cfg.Groups
+	r.mutex.Unlock()
+	return nil
+}
+
+// Dispatch serves QueryF requests, filtering group lookups by the caller's address.
+func (r *Registry) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	ip := ""
+	if current, ok := CurrentFromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(current.RemoteAddr.String()); nil == err {
+			ip = host
+		}
+	}
+	d := &QueryFDispatcher{Impl: r.ForCaller(ip)}
+	return d.Dispatch(ctx, req, resp)
+}
+
+// ForCaller returns a QueryF answering group lookups for a caller at ip.
+func (r *Registry) ForCaller(ip string) QueryF {
+	return &registryQuery{r: r, ip: ip}
+}
+
+func (r *Registry) FindObjectById(id string, context map[string]string) ([]EndpointF, map[string]string, error) {
+	return r.ForCaller("").FindObjectById(id, context)
+}
+
+func (r *Registry) FindObjectById4Any(id string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	return r.ForCaller("").FindObjectById4Any(id, activeEp, inactiveEp, context)
+}
+
+func (r *Registry) FindObjectById4All(id string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	return r.ForCaller("").FindObjectById4All(id, activeEp, inactiveEp, context)
+}
+
+func (r *Registry) FindObjectByIdInSameGroup(id string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	return r.ForCaller("").FindObjectByIdInSameGroup(id, activeEp, inactiveEp, context)
+}
+
+func (r *Registry) FindObjectByIdInSameStation(id string, sStation string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	return r.ForCaller("").FindObjectByIdInSameStation(id, sStation, activeEp, inactiveEp, context)
+}
+
+func (r *Registry) FindObjectByIdInSameSet(id string, setId string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	return r.ForCaller("").FindObjectByIdInSameSet(id, setId, activeEp, inactiveEp, context)
+}
+
+func (r *Registry) groupOf(ip string) string {
+	addr := net.ParseIP(ip)
+	for key, group := range r.groups {
+		if key == ip {
+			return group
+		}
+		if _, network, err := net.ParseCIDR(key); nil == err && nil != addr && network.Contains(addr) {
+			return group
+		}
+	}
+	return ""
+}
+
+// find splits the endpoints of id accepted by match into active and inactive lists.
+func (r *Registry) find(id string, match func(*registryEndpoint) bool) ([]EndpointF, []EndpointF, int32) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	active := []EndpointF{}
+	inactive := []EndpointF{}
+	items, exist := r.objects[id]
+	for i := range items {
+		if !match(&items[i]) {
+			continue
+		}
+		if items[i].Inactive {
+			inactive = append(inactive, items[i].e)
+		} else {
+			active = append(active, items[i].e)
+		}
+	}
+	if !exist {
+		return active, inactive, -1
+	}
+	return active, inactive, 0
+}
+
+func setFindResult(activeEp *[]EndpointF, inactiveEp *[]EndpointF, active []EndpointF, inactive []EndpointF) {
+	if nil != activeEp {
+		*activeEp = active
+	}
+	if nil != inactiveEp {
+		*inactiveEp = inactive
+	}
+}
+
+type registryQuery struct {
+	r  *Registry
+	ip string
+}
+
+func matchAll(*registryEndpoint) bool {
+	return true
+}
+
+func (q *registryQuery) FindObjectById(id string, context map[string]string) ([]EndpointF, map[string]string, error) {
+	active, _, _ := q.r.find(id, matchAll)
+	return active, nil, nil
+}
+
+func (q *registryQuery) FindObjectById4Any(id string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	return q.FindObjectByIdInSameGroup(id, activeEp, inactiveEp, context)
+}
+
+func (q *registryQuery) FindObjectById4All(id string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	active, inactive, ret := q.r.find(id, matchAll)
+	setFindResult(activeEp, inactiveEp, active, inactive)
+	return ret, nil, nil
+}
+
+// FindObjectByIdInSameGroup returns the endpoints in the caller's group, or every endpoint
+// when the caller has no group or its group serves none of them.
+func (q *registryQuery) FindObjectByIdInSameGroup(id string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	q.r.mutex.RLock()
+	group := q.r.groupOf(q.ip)
+	q.r.mutex.RUnlock()
+	active, inactive, ret := q.r.find(id, func(e *registryEndpoint) bool { return e.Group == group })
+	if group == "" || (ret == 0 && len(active) == 0) {
+		active, inactive, ret = q.r.find(id, matchAll)
+	}
+	setFindResult(activeEp, inactiveEp, active, inactive)
+	return ret, nil, nil
+}
+
+func (q *registryQuery) FindObjectByIdInSameStation(id string, sStation string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	active, inactive, ret := q.r.find(id, func(e *registryEndpoint) bool { return e.Station == sStation })
+	setFindResult(activeEp, inactiveEp, active, inactive)
+	return ret, nil, nil
+}
+
+// FindObjectByIdInSameSet matches set ids of the form app.area.group, where a group of
+// "*" selects the whole area.
+func (q *registryQuery) FindObjectByIdInSameSet(id string, setId string, activeEp *[]EndpointF, inactiveEp *[]EndpointF, context map[string]string) (int32, map[string]string, error) {
+	parts := strings.Split(setId, ".")
+	if len(parts) != 3 {
+		setFindResult(activeEp, inactiveEp, []EndpointF{}, []EndpointF{})
+		return -1, nil, nil
+	}
+	match := func(e *registryEndpoint) bool {
+		if parts[2] == "*" {
+			return strings.HasPrefix(e.SetId, parts[0]+"."+parts[1]+".")
+		}
+		return e.SetId == setId
+	}
+	active, inactive, ret := q.r.find(id, match)
+	setFindResult(activeEp, inactiveEp, active, inactive)
+	return ret, nil, nil
+}
@@ -0,0 +1,100 @@
+package tarsgo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func startTestServer(t *testing.T, servant string, d Dispatcher) (*Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	s.AddServant(servant, d)
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	port := l.Addr().(*net.TCPAddr).Port
+	return s, fmt.Sprintf("%s@tcp -h 127.0.0.1 -p %d", servant, port)
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry(&RegistryConfig{
+		Objects: map[string][]RegistryEndpoint{
+			"Test.HelloServer.HelloObj": {
+				{Endpoint: "tcp -h 10.0.0.1 -p 10001", Group: "sz", Station: "sz-1", SetId: "app.sz.1"},
+				{Endpoint: "tcp -h 10.0.1.1 -p 10001", Group: "sh", Station: "sh-1", SetId: "app.sh.1"},
+				{Endpoint: "tcp -h 10.0.2.1 -p 10001", Group: "sz", SetId: "app.sz.2", Inactive: true},
+			},
+		},
+		Groups: map[string]string{"127.0.0.0/8": "sh"},
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	_, obj := startTestServer(t, "tars.tarsregistry.QueryObj", r)
+	q := NewQueryFProxy(obj, time.Second)
+
+	endpoints, _, err := q.FindObjectById("Test.HelloServer.HelloObj", nil)
+	if nil != err || len(endpoints) != 2 {
+		t.Fatalf("findObjectById: %v, %v", endpoints, err)
+	}
+	var active, inactive []EndpointF
+	ret, _, err := q.FindObjectById4All("Test.HelloServer.HelloObj", &active, &inactive, nil)
+	if nil != err || ret != 0 || len(active) != 2 || len(inactive) != 1 {
+		t.Fatalf("findObjectById4All: %d %v %v %v", ret, active, inactive, err)
+	}
+	ret, _, err = q.FindObjectByIdInSameGroup("Test.HelloServer.HelloObj", &active, &inactive, nil)
+	if nil != err || ret != 0 || len(active) != 1 || active[0].Host != "10.0.1.1" {
+		t.Fatalf("findObjectByIdInSameGroup: %d %v %v", ret, active, err)
+	}
+	ret, _, err = q.FindObjectByIdInSameStation("Test.HelloServer.HelloObj", "sz-1", &active, &inactive, nil)
+	if nil != err || ret != 0 || len(active) != 1 || active[0].Host != "10.0.0.1" {
+		t.Fatalf("findObjectByIdInSameStation: %d %v %v", ret, active, err)
+	}
+	ret, _, err = q.FindObjectByIdInSameSet("Test.HelloServer.HelloObj", "app.sz.*", &active, &inactive, nil)
+	if nil != err || ret != 0 || len(active) != 1 || len(inactive) != 1 || active[0].SetId != "app.sz.1" {
+		t.Fatalf("findObjectByIdInSameSet: %d %v %v %v", ret, active, inactive, err)
+	}
+	ret, _, err = q.FindObjectById4Any("Test.Missing.Obj", &active, &inactive, nil)
+	if nil != err || ret != -1 || len(active) != 0 {
+		t.Fatalf("findObjectById4Any: %d %v %v", ret, active, err)
+	}
+
+	c := NewClient("Test.HelloServer.HelloObj", time.Second, WithResolver(NewRegistryResolver(q)))
+	if endpoints := c.getEndpoints(); len(endpoints) != 2 || endpoints[0].Port != 10001 {
+		t.Fatalf("client resolved %v", endpoints)
+	}
+
+	// clients still resolve through the deprecated DefaultNamingService, once each
+	defer func(resolver Resolver, naming *QueryFProxy) {
+		DefaultResolver, DefaultNamingService = resolver, naming
+	}(DefaultResolver, DefaultNamingService)
+	counted := &countedDispatcher{Dispatcher: r}
+	_, countedObj := startTestServer(t, "tars.tarsregistry.QueryObj", counted)
+	DefaultResolver, DefaultNamingService = nil, NewQueryFProxy(countedObj, time.Second)
+	defer DefaultNamingService.TarsClient.Close()
+	c = NewClient("Test.HelloServer.HelloObj", time.Second)
+	defer c.Close()
+	if endpoints := c.getEndpoints(); len(endpoints) != 2 {
+		t.Fatalf("client resolved %v through DefaultNamingService", endpoints)
+	}
+	if calls := atomic.LoadInt64(&counted.calls); calls != 1 {
+		t.Fatalf("client made %d registry calls", calls)
+	}
+}
+
+// countedDispatcher counts the requests it passes on.
+type countedDispatcher struct {
+	Dispatcher
+	calls int64
+}
+
+func (d *countedDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	atomic.AddInt64(&d.calls, 1)
+	return d.Dispatcher.Dispatch(ctx, req, resp)
+}
@@ -0,0 +1,97 @@
+package tarsgo
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultRemoteLogInterval    = time.Second
+	defaultRemoteLogMaxBuffered = 10000
+)
+
+// RemoteLogConfig describes the log a RemoteLogger writes on the TARS log service.
+type RemoteLogConfig struct {
+	// App and Server own the log, File names it and SetDivision is the set of the server,
+	// if any. The log service writes it to "App.Server_File_20060102.log".
+	App         string
+	Server      string
+	File        string
+	SetDivision string
+	// Interval between batches, one second when not set.
+	Interval time.Duration
+	// MaxBuffered lines may wait for a batch, 10000 when not set; more are dropped.
+	MaxBuffered int
+}
+
+// RemoteLogger is an io.Writer sending what is written to it in batches to the TARS log
+// service, usually tars.tarslog.LogObj. Every Write is one line of the log, so it is
+// usually wrapped by NewWriterLogger.
+type RemoteLogger struct {
+	config  RemoteLogConfig
+	proxy   *LogFProxy
+	mutex   sync.Mutex
+	lines   []string
+	dropped int
+	flusher *flushLoop
+}
+
+// NewRemoteLogger logs to the log servant obj and starts sending in the background until
+// Close.
+func NewRemoteLogger(obj string, cfg RemoteLogConfig, opts ...ClientOption) *RemoteLogger {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultRemoteLogInterval
+	}
+	if cfg.MaxBuffered <= 0 {
+		cfg.MaxBuffered = defaultRemoteLogMaxBuffered
+	}
+	l := &RemoteLogger{
+		config: cfg,
+		proxy:  NewLogFProxy(obj, 3*time.Second, opts...),
+	}
+	l.flusher = startFlushLoop(cfg.Interval, func() { l.Flush() })
+	return l
+}
+
+func (l *RemoteLogger) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	if len(l.lines) < l.config.MaxBuffered {
+		l.lines = append(l.lines, string(p))
+	} else {
+		l.dropped++
+	}
+	l.mutex.Unlock()
+	return len(p), nil
+}
+
+// Flush sends the lines written since the last batch.
+func (l *RemoteLogger) Flush() error {
+	l.mutex.Lock()
+	lines, dropped := l.lines, l.dropped
+	l.lines, l.dropped = nil, 0
+	l.mutex.Unlock()
+	if dropped > 0 {
+		logMsg(LogLevelWarn, "Dropped lines of remote log", Field{"file", l.config.File}, Field{"count", dropped})
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	var info LogInfo
+	info.ResetDefautlt()
+	info.Appname = l.config.App
+	info.Servername = l.config.Server
+	info.SFilename = l.config.File
+	info.SFormat = "%Y%m%d"
+	info.Setdivision = l.config.SetDivision
+	if _, err := l.proxy.LoggerbyInfo(info, lines, nil); nil != err {
+		logMsg(LogLevelError, "Failed to send remote log", Field{"file", l.config.File}, Field{"count", len(lines)}, Field{FieldError, err})
+		return err
+	}
+	return nil
+}
+
+// Close sends the buffered lines and stops the logger.
+func (l *RemoteLogger) Close() error {
+	l.flusher.close(l.proxy.TarsClient.Close)
+	return nil
+}
@@ -0,0 +1,57 @@
+package tarsgo
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logRecorder is a stand-in LogObj keeping what was logged.
+type logRecorder struct {
+	mutex   sync.Mutex
+	info    LogInfo
+	batches [][]string
+}
+
+func (l *logRecorder) Logger(app string, server string, file string, format string, buffer []string, context map[string]string) (map[string]string, error) {
+	return l.LoggerbyInfo(LogInfo{Appname: app, Servername: server, SFilename: file, SFormat: format}, buffer, context)
+}
+
+func (l *logRecorder) LoggerbyInfo(info LogInfo, buffer []string, context map[string]string) (map[string]string, error) {
+	l.mutex.Lock()
+	l.info = info
+	l.batches = append(l.batches, buffer)
+	l.mutex.Unlock()
+	return nil, nil
+}
+
+func TestRemoteLogger(t *testing.T) {
+	recorder := new(logRecorder)
+	_, logObj := startTestServer(t, "tars.tarslog.LogObj", &LogFDispatcher{recorder})
+
+	r := NewRemoteLogger(logObj, RemoteLogConfig{App: "Test", Server: "EchoServer", File: "access", Interval: time.Hour, MaxBuffered: 3})
+	logger := NewWriterLogger(r)
+	for _, sid := range []int32{1, 2, 3, 4} {
+		logger.Log(LogLevelWarn, fmt.Sprintf("Missing session:%d, maybe deleted by timeout task.", sid))
+	}
+	if err := r.Flush(); nil != err {
+		t.Fatal(err)
+	}
+	r.Write([]byte("last\n"))
+	r.Close()
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	info := recorder.info
+	if info.Appname != "Test" || info.Servername != "EchoServer" || info.SFilename != "access" || !info.BHasSufix || info.SSepar != "|" {
+		t.Fatalf("unexpected log info %+v", info)
+	}
+	if len(recorder.batches) != 2 || len(recorder.batches[0]) != 3 || recorder.batches[1][0] != "last\n" {
+		t.Fatalf("unexpected batches %q", recorder.batches)
+	}
+	if line := recorder.batches[0][2]; !strings.HasSuffix(line, "|WARN|Missing session:3, maybe deleted by timeout task.\n") {
+		t.Fatalf("unexpected line %q", line)
+	}
+}
@@ -0,0 +1,105 @@
+package tarsgo
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries failed calls. Only functions marked with
+// WithIdempotent are retried, and every attempt goes to another endpoint while untried
+// ones are left. All attempts share the deadline of the call.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the jittered exponential delay between attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Retryable tells whether a failure is worth another attempt, DefaultRetryable when nil.
+	Retryable func(err error) bool
+	// Budget limits the retries. When nil every client gets a budget of its own allowing
+	// one retry per ten calls.
+	Budget *RetryBudget
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  10 * time.Millisecond,
+	MaxBackoff:  100 * time.Millisecond,
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy for one client.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// DefaultRetryable retries lost connections and calls the server refused because it was
+// overloaded or could not queue them.
+func DefaultRetryable(err error) bool {
+	if errors.Is(err, ErrConnectionLost) {
+		return true
+	}
+	switch ErrorCode(err) {
+	case TarsServerOverload, TarsServerQueueTimeout:
+		return true
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if nil != p.Retryable {
+		return p.Retryable(err)
+	}
+	return DefaultRetryable(err)
+}
+
+// RetryBudget limits retries to a fraction of the calls made, so that a failing server
+// is not flooded with them. It may be shared by several clients.
+type RetryBudget struct {
+	mutex  sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+// NewRetryBudget allows ratio retries per call, saving up at most burst of them.
+func NewRetryBudget(ratio float64, burst int) *RetryBudget {
+	return &RetryBudget{ratio: ratio, max: float64(burst), tokens: float64(burst)}
+}
+
+func (b *RetryBudget) deposit() {
+	b.mutex.Lock()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+	b.mutex.Unlock()
+}
+
+func (b *RetryBudget) withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// jitterBackoff doubles min per attempt up to max and picks a random delay in its upper half.
+func jitterBackoff(min, max time.Duration, attempt int) time.Duration {
+	d := min
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
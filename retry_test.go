@@ -0,0 +1,67 @@
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// overloadedDispatcher counts the calls it refuses as overloaded.
+type overloadedDispatcher struct {
+	calls *int64
+}
+
+func (d overloadedDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	atomic.AddInt64(d.calls, 1)
+	return NewTarsError(TarsServerOverload, "busy")
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var calls1, calls2 int64
+	_, obj1 := startTestServer(t, "Test.EchoServer.EchoObj", overloadedDispatcher{&calls1})
+	_, obj2 := startTestServer(t, "Test.EchoServer.EchoObj", overloadedDispatcher{&calls2})
+	_, endpoints2, _ := ParseProxy(obj2)
+	policy := RetryPolicy{MaxAttempts: 3, Budget: NewRetryBudget(0, 2)}
+	c := NewClient(obj1+":"+endpoints2[0].String(), time.Second, WithIdempotent("echo"), WithRetryPolicy(policy))
+	defer c.Close()
+
+	// the first call retries on the other endpoint and then spends the budget
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); ErrorCode(err) != TarsServerOverload {
+		t.Fatalf("expected overload, got %v", err)
+	}
+	if calls1 == 0 || calls2 == 0 || calls1+calls2 != 3 {
+		t.Fatalf("attempts were spread as %d and %d", calls1, calls2)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); ErrorCode(err) != TarsServerOverload {
+			t.Fatalf("expected overload, got %v", err)
+		}
+	}
+	if calls1+calls2 != 5 {
+		t.Fatalf("%d attempts made with an empty budget", calls1+calls2)
+	}
+	if _, err := c.Invoke(JCENORMAL, "other", bytes.NewBufferString("hello"), nil); ErrorCode(err) != TarsServerOverload || calls1+calls2 != 6 {
+		t.Fatalf("call not marked idempotent was retried: %v", err)
+	}
+
+	_, echo := startTestServer(t, "Test.EchoServer.EchoObj", echoDispatcher{})
+	_, endpoints, _ := ParseProxy(echo)
+	policy = RetryPolicy{MaxAttempts: 2, Budget: NewRetryBudget(1, 1)}
+	c = NewClient(obj1+":"+endpoints[0].String(), time.Second, WithIdempotent("echo"), WithRetryPolicy(policy))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+		if nil != err || string(resp.SBuffer) != "hello" {
+			t.Fatalf("retried call returned %v, %v", resp, err)
+		}
+	}
+
+	c1, c2 := NewClient(echo, time.Second), NewClient(echo, time.Second)
+	defer c1.Close()
+	defer c2.Close()
+	if nil == c1.retryPolicy.Budget || c1.retryPolicy.Budget == c2.retryPolicy.Budget || nil != DefaultRetryPolicy.Budget {
+		t.Fatal("clients without a budget of their own share one")
+	}
+}
@@ -1,15 +1,12 @@
 package tarsgo
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"net"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -21,121 +18,145 @@ const (
 	JCEONEWAY = uint8(1)
 )
 
+const maxPacketLength = 10 * 1024 * 1024
+
 var ErrTarsRPCTimeout = errors.New("Tars RPC timeout")
+var ErrNoRPCChannel = errors.New("No rpc channel available")
+var ErrNoEndpoint = errors.New("No endpoint available")
+var ErrInvalidPacketLength = errors.New("Invalid packet length")
+var ErrClientClosed = errors.New("Tars client closed")
+var errCallCancelled = errors.New("Tars call cancelled")
+
+// ErrConnectionLost fails calls whose request was sent on a connection that broke before
+// the response arrived; the server may or may not have processed the request.
+var ErrConnectionLost = errors.New("Tars rpc connection lost")
 
 type rpcSession struct {
 	ID int32
 	ch chan *ResponsePacket
 }
 
-type rpcChannel struct {
-	Conn    net.Conn
-	ch      chan TarsStruct
-	idx     int
-	counter int64
-	running bool
-}
-
-func parseEndpoint(s string) (EndpointF, error) {
-	e := EndpointF{}
-	fields := strings.Fields(s)
-	if strings.EqualFold(fields[0], "tcp") {
-		e.Istcp = 1
+// readPacket reads one length-prefixed TARS packet and returns its body.
+func readPacket(r io.Reader) ([]byte, error) {
+	lenBuffer := make([]byte, 4)
+	_, err := io.ReadFull(r, lenBuffer)
+	if nil != err {
+		return nil, err
 	}
-	restField := fields[1:]
-	for i := 0; i < len(restField); i += 2 {
-		switch restField[i] {
-		case "-h":
-			e.Host = restField[i+1]
-		case "-p":
-			i, err := strconv.ParseInt(restField[i+1], 10, 32)
-			if err != nil {
-				return e, err
-			}
-			e.Port = int32(i)
-		case "-t":
-			i, err := strconv.ParseInt(restField[i+1], 10, 32)
-			if err != nil {
-				return e, err
-			}
-			e.Timeout = int32(i)
-		case "-g":
-			i, err := strconv.ParseInt(restField[i+1], 10, 32)
-			if err != nil {
-				return e, err
-			}
-			e.Grid = int32(i)
-		case "-f":
-			i, err := strconv.ParseInt(restField[i+1], 10, 32)
-			if err != nil {
-				return e, err
-			}
-			e.GridFlag = int32(i)
-		case "-w":
-			i, err := strconv.ParseInt(restField[i+1], 10, 32)
-			if err != nil {
-				return e, err
-			}
-			e.Weight = int32(i)
-		case "-v":
-			i, err := strconv.ParseInt(restField[i+1], 10, 32)
-			if err != nil {
-				return e, err
-			}
-			e.WeightType = int32(i)
-		case "-l":
-			i, err := strconv.ParseInt(restField[i+1], 10, 32)
-			if err != nil {
-				return e, err
-			}
-			e.Cpuload = int32(i)
-		case "-m":
-			i, err := strconv.ParseInt(restField[i+1], 10, 64)
-			if err != nil {
-				return e, err
-			}
-			e.Sampletime = i
-		case "-d":
-			e.ContainerName = restField[i+1]
-		default:
-			log.Printf("Unknown arg:%s", restField[i])
-		}
-
+	hlen := binary.BigEndian.Uint32(lenBuffer)
+	if hlen < 4 || hlen > maxPacketLength {
+		return nil, fmt.Errorf("%w:%d", ErrInvalidPacketLength, hlen)
+	}
+	b := make([]byte, int(hlen-4))
+	_, err = io.ReadFull(r, b)
+	if nil != err {
+		return nil, err
 	}
-	return e, nil
+	return b, nil
+}
+
+// encodePacket encodes packet behind its 4 byte length header.
+func encodePacket(packet TarsEncoder) []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	packet.Encode(&buf)
+	binary.BigEndian.PutUint32(buf.Bytes(), uint32(buf.Len()))
+	return buf.Bytes()
 }
 
+// Client calls one servant. It is safe for concurrent use; Timeout and MaxConn must not
+// be changed once calls started.
 type Client struct {
 	//Addr    string
 	servant string
 	Timeout time.Duration
+	// MaxConn is the number of connections kept to each endpoint, 5 when not set.
 	MaxConn int
 
-	endpoints []EndpointF
-	clients   []*rpcChannel
-	sessions  map[int32]*rpcSession
-	sid       int32
+	endpoints  []EndpointF
+	pools      map[string]*connPool
+	poolList   []*connPool
+	poolCursor uint32
+	connReady  chan struct{}
+	sessions   map[int32]*rpcSession
+	abandoned  map[int32]struct{}
+	sid        int32
+
+	resolver     Resolver
+	stopWatch    func()
+	tlsConfig    *TLSConfig
+	poolConfig   PoolConfig
+	idempotent   map[string]bool
+	retryPolicy  RetryPolicy
+	hedgePolicy  *HedgePolicy
+	interceptors []ClientInterceptor
+	metrics      []Metrics
+	logger       Logger
+	invoker      Invoker
+	latencies    map[string]*latencyWindow
+
+	// closing rejects new calls, inflight counts the admitted ones and drained is
+	// closed when the last of them returns after closing started.
+	closing   bool
+	inflight  int
+	drained   chan struct{}
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+	routines  sync.WaitGroup
+
+	sessionMutex sync.Mutex
+	poolsMutex   sync.Mutex
+	closeMutex   sync.Mutex
+}
+
+// ClientOption configures a Client created by NewClient.
+type ClientOption func(*Client)
+
+// WithResolver makes the client resolve its servant through r instead of DefaultResolver.
+func WithResolver(r Resolver) ClientOption {
+	return func(c *Client) {
+		c.resolver = r
+	}
+}
+
+// WithIdempotent marks funcs as safe to call more than once, so that their failed calls
+// are retried as the client's RetryPolicy allows.
+func WithIdempotent(funcs ...string) ClientOption {
+	return func(c *Client) {
+		if nil == c.idempotent {
+			c.idempotent = make(map[string]bool)
+		}
+		for _, f := range funcs {
+			c.idempotent[f] = true
+		}
+	}
+}
 
-	sessionMutex   sync.Mutex
-	clientsMutex   sync.Mutex
-	endpointCursor int32
+func (c *Client) getEndpoints() []EndpointF {
+	c.poolsMutex.Lock()
+	defer c.poolsMutex.Unlock()
+	return c.endpoints
 }
 
-func (c *Client) selectEndpoint() EndpointF {
-	cursor := c.endpointCursor
-	if int(cursor) < len(c.endpoints) {
-		atomic.AddInt32(&c.endpointCursor, 1)
-		return c.endpoints[cursor]
+func (c *Client) setEndpoints(endpoints []EndpointF) {
+	c.poolsMutex.Lock()
+	c.endpoints = endpoints
+	if nil != c.pools {
+		c.syncPoolsLocked()
 	}
-	atomic.StoreInt32(&c.endpointCursor, 1)
-	return c.endpoints[0]
+	c.poolsMutex.Unlock()
+}
+
+func (c *Client) nextRequestID() int32 {
+	return atomic.AddInt32(&c.sid, 1)
 }
 
 func (c *Client) newRPCSession(sid int32) *rpcSession {
 	c.sessionMutex.Lock()
 	s := new(rpcSession)
 	s.ID = sid
-	s.ch = make(chan *ResponsePacket)
+	s.ch = make(chan *ResponsePacket, 1)
 	c.sessions[sid] = s
 	c.sessionMutex.Unlock()
 	return s
@@ -145,6 +166,36 @@ func (c *Client) closeRPCSession(sid int32) {
 	delete(c.sessions, sid)
 	c.sessionMutex.Unlock()
 }
+
+// abandonRPCSession closes a session whose response is no longer wanted; the response
+// is dropped quietly if it arrives before deadline.
+func (c *Client) abandonRPCSession(sid int32, deadline time.Time) {
+	c.sessionMutex.Lock()
+	delete(c.sessions, sid)
+	c.abandoned[sid] = struct{}{}
+	c.sessionMutex.Unlock()
+	c.goroutine(func() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-c.done:
+		}
+		c.sessionMutex.Lock()
+		delete(c.abandoned, sid)
+		c.sessionMutex.Unlock()
+	})
+}
+
+// dropAbandoned reports whether sid belonged to an abandoned session, forgetting it.
+func (c *Client) dropAbandoned(sid int32) bool {
+	c.sessionMutex.Lock()
+	defer c.sessionMutex.Unlock()
+	_, exist := c.abandoned[sid]
+	delete(c.abandoned, sid)
+	return exist
+}
+
 func (c *Client) getRPCSession(sid int32) *rpcSession {
 	c.sessionMutex.Lock()
 	s, exist := c.sessions[sid]
@@ -155,173 +206,292 @@ func (c *Client) getRPCSession(sid int32) *rpcSession {
 	return s
 }
 
-func (c *Client) closeRPCChannel(channel *rpcChannel) {
-	c.clientsMutex.Lock()
-	channel.Conn.Close()
-	channel.running = false
-	close(channel.ch)
-	c.clients[channel.idx] = nil
-	c.clientsMutex.Unlock()
-}
-
-func (c *Client) rpcChannelRead(channel *rpcChannel) {
-	bufReader := bufio.NewReader(channel.Conn)
-	lenBuffer := make([]byte, 4)
-	var err error
-	for channel.running {
-		_, err = io.ReadAtLeast(bufReader, lenBuffer, len(lenBuffer))
-		if nil != err {
-			break
-		}
-		hlen := int32(0)
-		binary.Read(bytes.NewBuffer(lenBuffer), binary.BigEndian, &hlen)
-		b := make([]byte, int(hlen-4))
-		_, err = io.ReadAtLeast(bufReader, b, len(b))
-		if nil != err {
-			break
+// call sends packet on rc and waits for its response until deadline. Closing cancel
+// abandons the call with errCallCancelled.
+func (c *Client) call(rc *rpcChannel, packet *RequestPacket, deadline time.Time, cancel <-chan struct{}) (*ResponsePacket, error) {
+	frame := encodePacket(packet)
+	if rc.pool.endpoint.Istcp == EndpointUDP {
+		if err := checkUDPPacketSize(frame); nil != err {
+			return nil, err
 		}
-		var resp ResponsePacket
-		err = resp.Decode(bytes.NewBuffer(b))
-		if nil == err {
-			s := c.getRPCSession(resp.IRequestId)
-			if nil != s {
-				s.ch <- &resp
-			} else {
-				log.Printf("Missing session:%d, maybe deleted by timeout task.", resp.IRequestId)
-			}
-		} else {
-			log.Printf("Docode 'ResponsePacket' error:%v", err)
+	}
+	sid := packet.IRequestId
+	session := c.newRPCSession(sid)
+	abandoned := false
+	defer func() {
+		if !abandoned {
+			c.closeRPCSession(sid)
 		}
+	}()
+	atomic.AddInt64(&rc.pending, 1)
+	defer atomic.AddInt64(&rc.pending, -1)
+	if packet.SFuncName != probeFuncName {
+		defer rc.touch()
 	}
-	c.closeRPCChannel(channel)
-	if nil != err {
-		log.Printf("RPCChannel:%d read close for reason:%v", channel.idx, err)
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	if err := rc.send(frame, timer.C); nil != err {
+		return nil, err
+	}
+	if packet.CPacketType == JCEONEWAY {
+		// the server does not answer oneway calls
+		return nil, nil
+	}
+	select {
+	case resp := <-session.ch:
+		return resp, nil
+	case <-timer.C:
+		return nil, ErrTarsRPCTimeout
+	case <-rc.done:
+		// the reader delivers every response it got before closing the connection
+		select {
+		case resp := <-session.ch:
+			return resp, nil
+		case <-c.done:
+			return nil, ErrClientClosed
+		default:
+			return nil, ErrConnectionLost
+		}
+	case <-c.done:
+		return nil, ErrClientClosed
+	case <-cancel:
+		abandoned = true
+		c.abandonRPCSession(sid, deadline)
+		return nil, errCallCancelled
 	}
+}
 
+// goroutine runs f in a goroutine that Close and Shutdown wait for.
+func (c *Client) goroutine(f func()) {
+	c.routines.Add(1)
+	go func() {
+		defer c.routines.Done()
+		f()
+	}()
 }
 
-func (c *Client) rpcChannelWrite(channel *rpcChannel) {
-	var err error
-	for channel.running {
-		select {
-		case packet := <-channel.ch:
-			if nil != packet {
-				var buf bytes.Buffer
-				buf.Write(make([]byte, 4))
-				packet.Encode(&buf)
-				vlen := buf.Len()
-				binary.BigEndian.PutUint32(buf.Bytes(), uint32(vlen))
-				_, err = channel.Conn.Write(buf.Bytes())
-				if nil != err {
-					log.Printf("Failed to write rpc channel:%v", err)
-					break
-				}
-				channel.counter++
-			} else {
-				return
-			}
-		}
+// begin admits a call unless the client is closing; every admitted call must end.
+func (c *Client) begin() error {
+	c.closeMutex.Lock()
+	defer c.closeMutex.Unlock()
+	if c.closing {
+		return ErrClientClosed
 	}
-	c.closeRPCChannel(channel)
-	if nil != err {
-		log.Printf("RPCChannel:%d write close for reason:%v", channel.idx, err)
+	c.inflight++
+	return nil
+}
+
+func (c *Client) end() {
+	c.closeMutex.Lock()
+	c.inflight--
+	if c.closing && c.inflight == 0 {
+		close(c.drained)
 	}
+	c.closeMutex.Unlock()
 }
 
-func (c *Client) newRPCChannel(idx int) *rpcChannel {
-	rc := new(rpcChannel)
-	rc.idx = idx
-	rc.running = true
-	var err error
-	endpoint := c.selectEndpoint()
-	addr := fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
-	rc.Conn, err = net.Dial("tcp", addr)
-	if nil != err {
-		log.Printf("Failed to connect server:%s for reason:%v", addr, err)
-		return nil
+// startClosing rejects new calls and returns a channel closed once the in-flight ones returned.
+func (c *Client) startClosing() chan struct{} {
+	c.closeMutex.Lock()
+	defer c.closeMutex.Unlock()
+	if !c.closing {
+		c.closing = true
+		if c.inflight == 0 {
+			close(c.drained)
+		}
 	}
-	rc.ch = make(chan TarsStruct, 100)
-	go c.rpcChannelWrite(rc)
-	go c.rpcChannelRead(rc)
-	c.clientsMutex.Lock()
-	c.clients[idx] = rc
-	c.clientsMutex.Unlock()
-	return rc
+	return c.drained
 }
 
-func (c *Client) getRPCChannel() *rpcChannel {
-	minCounter := int64(0)
-	minIdx := 0
-	for i, client := range c.clients {
-		if nil == client {
-			client = c.newRPCChannel(i)
-			if nil == client {
-				continue
-			} else {
-				return client
-			}
+// terminate stops watching the resolver and closes every connection.
+func (c *Client) terminate() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if nil != c.stopWatch {
+			c.stopWatch()
 		}
-		if minCounter > client.counter {
-			minCounter = client.counter
-			minIdx = i
+		c.poolsMutex.Lock()
+		c.closed = true
+		pools := c.poolList
+		c.pools = nil
+		c.poolList = nil
+		c.poolsMutex.Unlock()
+		for _, p := range pools {
+			p.close()
 		}
+	})
+}
+
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the client at once; calls still in flight fail with ErrClientClosed.
+// It returns after every background goroutine of the client has exited.
+func (c *Client) Close() error {
+	c.startClosing()
+	c.terminate()
+	c.routines.Wait()
+	return nil
+}
+
+// Shutdown rejects new calls and waits for the in-flight ones to finish before closing
+// the client. When ctx expires first the remaining calls are failed as by Close, and
+// ctx.Err() is returned.
+func (c *Client) Shutdown(ctx context.Context) error {
+	var err error
+	select {
+	case <-c.startClosing():
+	case <-ctx.Done():
+		err = ctx.Err()
 	}
-	return c.clients[minIdx]
+	c.terminate()
+	c.routines.Wait()
+	return err
 }
 
+// Invoke calls funcName with the encoded arguments in req. When the server answers with
+// a non-zero IRet the response is returned together with a *TarsError. Oneway calls
+// (ctype JCEONEWAY) return a nil response as soon as the request is written.
 func (c *Client) Invoke(ctype uint8, funcName string, req *bytes.Buffer, ctx map[string]string) (*ResponsePacket, error) {
+	return c.InvokeContext(context.Background(), ctype, funcName, req, ctx)
+}
+
+// InvokeContext is Invoke bounded by the deadline and cancellation of ctx as well as by
+// Timeout. The call passes through the client's interceptors.
+func (c *Client) InvokeContext(ctx context.Context, ctype uint8, funcName string, req *bytes.Buffer, reqContext map[string]string) (*ResponsePacket, error) {
 	packet := RequestPacket{}
 	packet.SBuffer = req.Bytes()
 	packet.IVersion = 1
 	packet.SServantName = c.servant
 	packet.SFuncName = funcName
-	packet.IRequestId = atomic.AddInt32(&c.sid, 1)
-	packet.Context = ctx
+	packet.IRequestId = c.nextRequestID()
+	packet.Context = reqContext
+	packet.CPacketType = ctype
 	packet.IMessageType = int32(ctype)
 	packet.ITimeout = 1000
-	session := c.newRPCSession(packet.IRequestId)
-	rpcConn := c.getRPCChannel()
-	rpcConn.ch <- &packet
-	var err error
-	var resp *ResponsePacket
-	select {
-	case resp = <-session.ch:
-		break
-	case <-time.After(c.Timeout):
-		err = ErrTarsRPCTimeout
+	if err := c.begin(); nil != err {
+		return nil, err
 	}
+	defer c.end()
+	return c.invoker(ctx, &packet)
+}
+
+// peerKey marks a context asking invoke to store the endpoint a call went to.
+type peerKey struct{}
 
-	if nil == resp && nil == err {
-		err = fmt.Errorf("No response recevied, maybe timeout")
+// invoke sends packet, retrying and hedging it as configured.
+func (c *Client) invoke(ctx context.Context, packet *RequestPacket) (*ResponsePacket, error) {
+	deadline := time.Now().Add(c.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	funcName := packet.SFuncName
+	policy := &c.retryPolicy
+	budget := policy.Budget
+	budget.deposit()
+	tried := make(map[*connPool]bool)
+	for attempt := 1; ; {
+		rpcConn, err := c.getRPCChannel(deadline, tried)
+		if nil != err {
+			return nil, err
+		}
+		tried[rpcConn.pool] = true
+		var resp *ResponsePacket
+		if nil != c.hedgePolicy && c.idempotent[funcName] {
+			rpcConn, resp, err = c.hedgedCall(ctx, rpcConn, packet, deadline, tried)
+		} else {
+			resp, err = c.call(rpcConn, packet, deadline, ctx.Done())
+		}
+		if peer, ok := ctx.Value(peerKey{}).(*EndpointF); ok {
+			*peer = rpcConn.pool.endpoint
+		}
+		if err == errCallCancelled {
+			return nil, ctx.Err()
+		}
+		if err == ErrConnectionClosed {
+			// the request was never written, so it is safe to send it elsewhere
+			packet.IRequestId = c.nextRequestID()
+			continue
+		}
+		if nil == err && nil != resp && resp.IRet != TarsServerSuccess {
+			err = &TarsError{Code: resp.IRet, Desc: resp.SResultDesc}
+		}
+		if nil == err || !c.idempotent[funcName] || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return resp, err
+		}
+		delay := jitterBackoff(policy.MinBackoff, policy.MaxBackoff, attempt-1)
+		if time.Now().Add(delay).After(deadline) || !budget.withdraw() {
+			return resp, err
+		}
+		c.log(LogLevelInfo, "Retry call", Field{FieldFunc, funcName}, Field{"attempt", attempt}, Field{FieldError, err})
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrClientClosed
+		}
+		attempt++
+		packet.IRequestId = c.nextRequestID()
 	}
-	c.closeRPCSession(packet.IRequestId)
-	return resp, err
 }
 
-func NewClient(addr string, timeout time.Duration) *Client {
-	c := &Client{}
-	ss := strings.Split(addr, "@")
-	if len(ss) == 2 {
-		c.servant = ss[0]
-		endpoints := strings.Split(ss[1], ":")
-		for _, endpoint := range endpoints {
-			e, err := parseEndpoint(endpoint)
+func NewClient(addr string, timeout time.Duration, opts ...ClientOption) *Client {
+	c := &Client{poolConfig: DefaultPoolConfig, retryPolicy: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if nil == c.retryPolicy.Budget {
+		c.retryPolicy.Budget = NewRetryBudget(0.1, 10)
+	}
+	servant, endpoints, err := ParseProxy(addr)
+	if nil != err {
+		c.log(LogLevelError, "Invalid proxy", Field{"proxy", addr}, Field{FieldError, err})
+	}
+	c.servant = servant
+	c.Timeout = timeout
+	c.connReady = make(chan struct{})
+	c.sessions = make(map[int32]*rpcSession)
+	c.abandoned = make(map[int32]struct{})
+	c.drained = make(chan struct{})
+	c.done = make(chan struct{})
+	var invoker Invoker = c.invoke
+	if len(c.metrics) > 0 {
+		invoker = c.measure(invoker)
+	}
+	c.invoker = chainClientInterceptors(c.interceptors, invoker)
+	if strings.Contains(addr, "@") {
+		c.endpoints = endpoints
+	} else {
+		if nil == c.resolver {
+			c.resolver = DefaultResolver
+		}
+		if nil == c.resolver && nil != DefaultNamingService {
+			c.resolver = NewRegistryResolver(DefaultNamingService)
+		}
+		if nil != c.resolver {
+			endpoints, err := c.resolver.Resolve(servant)
 			if nil != err {
-				log.Printf("Invalid endpoint %s for reason:%v", endpoint, err)
-			} else {
-				c.endpoints = append(c.endpoints, e)
+				c.log(LogLevelError, "Failed to resolve servant", Field{FieldError, err})
+			}
+			c.endpoints = endpoints
+			c.stopWatch, err = c.resolver.Watch(servant, c.setEndpoints)
+			if nil != err {
+				c.log(LogLevelError, "Failed to watch servant", Field{FieldError, err})
 			}
-		}
-	} else {
-		c.servant = addr
-		if nil != DefaultNamingService {
-			c.endpoints, _, _ = DefaultNamingService.FindObjectById(addr, nil)
 		}
 	}
-	c.Timeout = timeout
 	//c.Servant = servant
-
-	c.clients = make([]*rpcChannel, 5)
-	c.sessions = make(map[int32]*rpcSession)
+	for _, m := range c.metrics {
+		if tracker, ok := m.(ClientTracker); ok {
+			tracker.TrackClient(c)
+		}
+	}
 	return c
 }
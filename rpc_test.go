@@ -0,0 +1,277 @@
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// checkGoroutines fails when the number of goroutines does not drop back to before.
+func checkGoroutines(t *testing.T, before int) {
+	deadline := time.Now().Add(3 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			buf = buf[:runtime.Stack(buf, true)]
+			t.Fatalf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-before, buf)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(200*time.Millisecond))
+	// an endpoint that never accepts keeps a dial and its backoff pending
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+	obj += fmt.Sprintf(":tcp -h 127.0.0.1 -p %d", addr.Port)
+
+	before := runtime.NumGoroutine()
+	c := NewClient(obj, time.Second, WithPoolConfig(PoolConfig{
+		ProbeInterval: time.Second,
+		MinBackoff:    time.Second,
+		MaxBackoff:    time.Second,
+	}))
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+		result <- err
+	}()
+	waitFor(t, "call in flight", func() bool {
+		for _, st := range c.PoolStats() {
+			if st.InFlight > 0 {
+				return true
+			}
+		}
+		return false
+	})
+	if err = c.Close(); nil != err {
+		t.Fatal(err)
+	}
+	if err = <-result; err != ErrClientClosed {
+		t.Fatalf("in-flight call returned %v", err)
+	}
+	if _, err = c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); err != ErrClientClosed {
+		t.Fatalf("call after close returned %v", err)
+	}
+	c.Close()
+	checkGoroutines(t, before)
+}
+
+func TestClientShutdown(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(100*time.Millisecond))
+	before := runtime.NumGoroutine()
+	c := NewClient(obj, time.Second)
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+		result <- err
+	}()
+	waitFor(t, "call in flight", func() bool {
+		stats := c.PoolStats()
+		return len(stats) == 1 && stats[0].InFlight > 0
+	})
+	if err := c.Shutdown(context.Background()); nil != err {
+		t.Fatal(err)
+	}
+	if err := <-result; nil != err {
+		t.Fatalf("in-flight call failed during shutdown: %v", err)
+	}
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); err != ErrClientClosed {
+		t.Fatalf("call after shutdown returned %v", err)
+	}
+	checkGoroutines(t, before)
+
+	c = NewClient(obj, time.Second)
+	go func() {
+		_, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+		result <- err
+	}()
+	waitFor(t, "call in flight", func() bool {
+		stats := c.PoolStats()
+		return len(stats) == 1 && stats[0].InFlight > 0
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if err := <-result; err != ErrClientClosed {
+		t.Fatalf("expired shutdown left call with %v", err)
+	}
+}
+
+func TestClientStress(t *testing.T) {
+	_, obj1 := startTestServer(t, "Test.EchoServer.EchoObj", echoDispatcher{})
+	_, obj2 := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(time.Millisecond))
+	_, endpoints1, _ := ParseProxy(obj1)
+	_, endpoints2, _ := ParseProxy(obj2)
+	r := NewMemoryResolver()
+	r.Set("Test.EchoServer.EchoObj", endpoints1...)
+	c := NewClient("Test.EchoServer.EchoObj", time.Second, WithResolver(r), WithMaxConn(2))
+	defer c.Close()
+
+	stop := make(chan struct{})
+	var background sync.WaitGroup
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			switch i % 3 {
+			case 0:
+				r.Set("Test.EchoServer.EchoObj", endpoints2...)
+			case 1:
+				r.Set("Test.EchoServer.EchoObj", append(endpoints1, endpoints2...)...)
+			default:
+				r.Set("Test.EchoServer.EchoObj", endpoints1...)
+			}
+			c.PoolStats()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				msg := fmt.Sprintf("hello %d %d", i, j)
+				resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString(msg), nil)
+				if nil != err {
+					t.Error(err)
+				} else if string(resp.SBuffer) != msg {
+					t.Errorf("response %q for request %q", resp.SBuffer, msg)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	background.Wait()
+}
+
+func TestClientCloseStress(t *testing.T) {
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", echoDispatcher{})
+	for round := 0; round < 20; round++ {
+		c := NewClient(obj, time.Second)
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					_, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+					if err == ErrClientClosed {
+						return
+					}
+					if nil != err && err != ErrConnectionClosed {
+						t.Error(err)
+					}
+				}
+			}()
+		}
+		time.Sleep(time.Duration(round%5) * time.Millisecond)
+		if round%2 == 0 {
+			c.Close()
+		} else {
+			c.Shutdown(context.Background())
+		}
+		wg.Wait()
+	}
+}
+
+// startDroppingServer accepts connections and drops each one once a request arrived.
+func startDroppingServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if nil != err {
+				return
+			}
+			go func() {
+				readPacket(conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return fmt.Sprintf("tcp -h 127.0.0.1 -p %d", l.Addr().(*net.TCPAddr).Port)
+}
+
+func TestConnectionLost(t *testing.T) {
+	dropping := startDroppingServer(t)
+	c := NewClient("Test.EchoServer.EchoObj@"+dropping, 5*time.Second)
+	defer c.Close()
+	start := time.Now()
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); err != ErrConnectionLost {
+		t.Fatalf("expected connection lost, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("lost call took %v to fail", time.Since(start))
+	}
+
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", echoDispatcher{})
+	c = NewClient(obj+":"+dropping, 5*time.Second, WithMaxConn(1), WithIdempotent("echo"))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+		if nil != err || string(resp.SBuffer) != "hello" {
+			t.Fatalf("idempotent call returned %v, %v", resp, err)
+		}
+	}
+}
+
+type countingDispatcher chan string
+
+func (d countingDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	d <- string(req.SBuffer)
+	resp.SBuffer = req.SBuffer
+	return nil
+}
+
+func TestOneway(t *testing.T) {
+	calls := make(countingDispatcher, 1)
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", calls)
+	c := NewClient(obj, time.Second)
+	defer c.Close()
+	start := time.Now()
+	resp, err := c.Invoke(JCEONEWAY, "echo", bytes.NewBufferString("hello"), nil)
+	if nil != err || nil != resp {
+		t.Fatalf("oneway call returned %v, %v", resp, err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("oneway call waited %v for a response", time.Since(start))
+	}
+	select {
+	case body := <-calls:
+		if body != "hello" {
+			t.Fatalf("server got %q", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("oneway call did not reach the server")
+	}
+	// the server sent no response, so a following call gets its own answer
+	resp, err = c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("world"), nil)
+	if nil != err || string(resp.SBuffer) != "world" {
+		t.Fatalf("normal call returned %v, %v", resp, err)
+	}
+	<-calls
+}
@@ -0,0 +1,386 @@
+package tarsgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var ErrServerClosed = errors.New("Tars server closed")
+
+// Dispatcher decodes a request for one servant, calls the implementation and fills in resp.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error
+}
+
+// Current describes the connection a request was received on.
+type Current struct {
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+}
+
+type currentKey struct{}
+
+// CurrentFromContext returns the Current of the request being dispatched.
+func CurrentFromContext(ctx context.Context) (*Current, bool) {
+	current, ok := ctx.Value(currentKey{}).(*Current)
+	return current, ok
+}
+
+// Server serves TARS requests for the servants added to it.
+type Server struct {
+	// TLS configures the ssl endpoints passed to ListenAndServe.
+	TLS *TLSConfig
+
+	mutex        sync.Mutex
+	servants     map[string]Dispatcher
+	interceptors []ServerInterceptor
+	handler      Handler
+	listeners    map[net.Listener]struct{}
+	conns        map[net.Conn]net.Listener
+	packets      map[net.PacketConn]struct{}
+	closed       bool
+	wg           sync.WaitGroup
+}
+
+func NewServer() *Server {
+	return &Server{
+		servants:  make(map[string]Dispatcher),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]net.Listener),
+		packets:   make(map[net.PacketConn]struct{}),
+	}
+}
+
+func (s *Server) AddServant(servant string, d Dispatcher) {
+	s.mutex.Lock()
+	s.servants[servant] = d
+	s.mutex.Unlock()
+}
+
+func (s *Server) getServant(servant string) Dispatcher {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.servants[servant]
+}
+
+// AddInterceptor adds interceptors run around every request except tars_ping; the first
+// one added is the outermost.
+func (s *Server) AddInterceptor(interceptors ...ServerInterceptor) {
+	s.mutex.Lock()
+	s.interceptors = append(s.interceptors, interceptors...)
+	s.handler = chainServerInterceptors(s.interceptors, s.dispatch)
+	s.mutex.Unlock()
+}
+
+func (s *Server) getHandler() Handler {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if nil == s.handler {
+		return s.dispatch
+	}
+	return s.handler
+}
+
+// dispatch passes req to the dispatcher of its servant.
+func (s *Server) dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	d := s.getServant(req.SServantName)
+	if nil == d {
+		return NewTarsError(TarsServerNoServantErr, "No servant:"+req.SServantName)
+	}
+	return d.Dispatch(ctx, req, resp)
+}
+
+// Listen opens a stream listener for an endpoint string such as "tcp -h 127.0.0.1 -p 10000"
+// or "unix -h /var/run/hello.sock". A stale unix socket file is removed first, while one
+// a live server still accepts on is reported as in use.
+func Listen(endpoint string) (net.Listener, error) {
+	e, err := ParseEndpoint(endpoint)
+	if nil != err {
+		return nil, err
+	}
+	switch e.Istcp {
+	case EndpointTCP:
+		return net.Listen("tcp", e.Address())
+	case EndpointUnix:
+		if err := removeStaleSocket(e.Host); nil != err {
+			return nil, err
+		}
+		return net.Listen("unix", e.Address())
+	default:
+		return nil, fmt.Errorf("Unsupported endpoint for listening:%s", endpoint)
+	}
+}
+
+// removeStaleSocket removes the socket file at path unless a server still accepts on it.
+func removeStaleSocket(path string) error {
+	fi, err := os.Stat(path)
+	if nil != err || fi.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if nil == err {
+		conn.Close()
+		return fmt.Errorf("listen unix %s: %w", path, syscall.EADDRINUSE)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("listen unix %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+func (s *Server) ListenAndServe(endpoint string) error {
+	l, pc, err := s.listen(endpoint)
+	if nil != err {
+		return err
+	}
+	if nil != pc {
+		return s.ServePacket(pc)
+	}
+	return s.Serve(l)
+}
+
+// listen opens endpoint for Serve, or for ServePacket if it is an udp endpoint.
+func (s *Server) listen(endpoint string) (net.Listener, net.PacketConn, error) {
+	e, err := ParseEndpoint(endpoint)
+	if nil != err {
+		return nil, nil, err
+	}
+	if e.Istcp == EndpointUDP {
+		pc, err := net.ListenPacket("udp", e.Address())
+		return nil, pc, err
+	}
+	if e.Istcp == EndpointSSL {
+		if nil == s.TLS {
+			return nil, nil, fmt.Errorf("No TLS config for endpoint:%s", endpoint)
+		}
+		cfg, err := s.TLS.ServerConfig()
+		if nil != err {
+			return nil, nil, err
+		}
+		l, err := net.Listen("tcp", e.Address())
+		if nil != err {
+			return nil, nil, err
+		}
+		return tls.NewListener(l, cfg), nil, nil
+	}
+	l, err := Listen(endpoint)
+	return l, nil, err
+}
+
+// Serve accepts connections on l until it fails or the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.listeners, l)
+		s.mutex.Unlock()
+	}()
+	for {
+		conn, err := l.Accept()
+		if nil != err {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = l
+		s.wg.Add(1)
+		s.mutex.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners, closes every connection and waits for their handlers to return.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	for pc := range s.packets {
+		pc.Close()
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// connections returns the remote addresses of the connections of s by the address of
+// the listener that accepted them.
+func (s *Server) connections() map[string][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	conns := make(map[string][]string)
+	for conn, l := range s.conns {
+		local := l.Addr().String()
+		conns[local] = append(conns[local], conn.RemoteAddr().String())
+	}
+	for _, remotes := range conns {
+		sort.Strings(remotes)
+	}
+	return conns
+}
+
+// ServePacket serves requests arriving as UDP datagrams on pc, one packet per datagram.
+func (s *Server) ServePacket(pc net.PacketConn) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		pc.Close()
+		return ErrServerClosed
+	}
+	s.packets[pc] = struct{}{}
+	s.wg.Add(1)
+	s.mutex.Unlock()
+	var handlers sync.WaitGroup
+	defer func() {
+		handlers.Wait()
+		s.mutex.Lock()
+		delete(s.packets, pc)
+		s.mutex.Unlock()
+		s.wg.Done()
+	}()
+	datagram := make([]byte, maxUDPPacketSize)
+	for {
+		n, addr, err := pc.ReadFrom(datagram)
+		if nil != err {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		b, err := unpackDatagram(datagram[:n])
+		if nil != err {
+			logMsg(LogLevelError, "Invalid datagram", Field{"remote", addr}, Field{FieldError, err})
+			continue
+		}
+		req := new(RequestPacket)
+		err = req.Decode(bytes.NewBuffer(b))
+		if nil != err {
+			logMsg(LogLevelError, "Failed to decode 'RequestPacket'", Field{"remote", addr}, Field{FieldError, err})
+			continue
+		}
+		ctx := context.WithValue(context.Background(), currentKey{}, &Current{LocalAddr: pc.LocalAddr(), RemoteAddr: addr})
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+			resp := s.handle(ctx, req)
+			if req.CPacketType == JCEONEWAY {
+				return
+			}
+			packet := encodePacket(resp)
+			if err := checkUDPPacketSize(packet); nil != err {
+				resp.IRet = TarsServerEncodeErr
+				resp.SResultDesc = err.Error()
+				resp.SBuffer = nil
+				packet = encodePacket(resp)
+			}
+			if _, err := pc.WriteTo(packet, addr); nil != err {
+				logMsg(LogLevelError, "Failed to write response", Field{FieldServant, req.SServantName}, Field{FieldRequestID, req.IRequestId}, Field{"remote", addr}, Field{FieldError, err})
+			}
+		}()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+	}()
+	current := &Current{LocalAddr: conn.LocalAddr(), RemoteAddr: conn.RemoteAddr()}
+	ctx := context.WithValue(context.Background(), currentKey{}, current)
+	var writeMutex sync.Mutex
+	var handlers sync.WaitGroup
+	defer handlers.Wait()
+	bufReader := bufio.NewReader(conn)
+	for {
+		b, err := readPacket(bufReader)
+		if nil != err {
+			return
+		}
+		req := new(RequestPacket)
+		err = req.Decode(bytes.NewBuffer(b))
+		if nil != err {
+			logMsg(LogLevelError, "Failed to decode 'RequestPacket'", Field{"remote", conn.RemoteAddr()}, Field{FieldError, err})
+			continue
+		}
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+			resp := s.handle(ctx, req)
+			if req.CPacketType == JCEONEWAY {
+				return
+			}
+			writeMutex.Lock()
+			_, err := conn.Write(encodePacket(resp))
+			writeMutex.Unlock()
+			if nil != err {
+				logMsg(LogLevelError, "Failed to write response", Field{FieldServant, req.SServantName}, Field{FieldRequestID, req.IRequestId}, Field{"remote", conn.RemoteAddr()}, Field{FieldError, err})
+			}
+		}()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req *RequestPacket) *ResponsePacket {
+	resp := &ResponsePacket{
+		IVersion:     req.IVersion,
+		CPacketType:  JCENORMAL,
+		IRequestId:   req.IRequestId,
+		IMessageType: req.IMessageType,
+	}
+	if req.SFuncName == "tars_ping" {
+		return resp
+	}
+	err := s.getHandler()(ctx, req, resp)
+	if nil != err {
+		var tarsErr *TarsError
+		if errors.As(err, &tarsErr) {
+			resp.IRet = tarsErr.Code
+			resp.SResultDesc = tarsErr.Desc
+		} else {
+			resp.IRet = TarsServerUnknownErr
+			resp.SResultDesc = err.Error()
+		}
+		resp.SBuffer = nil
+	}
+	return resp
+}
@@ -0,0 +1,144 @@
+package tarsgo
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultStatInterval = 60 * time.Second
+
+// DefaultStatIntervals are the latency bounds, in milliseconds, of StatMicMsgBody.IntervalCount.
+var DefaultStatIntervals = []int32{5, 10, 50, 100, 200, 500, 1000, 2000, 3000}
+
+// StatIntervalOverflow counts the calls slower than the last of DefaultStatIntervals.
+const StatIntervalOverflow = int32(math.MaxInt32)
+
+// StatConfig describes who reports statistics and how often.
+type StatConfig struct {
+	// Module is the reporting "app.server", MasterIp its address.
+	Module   string
+	MasterIp string
+	// SetDivision "name.area.group" is the set of the reporting server, if any.
+	SetDivision string
+	// Interval between reports, one minute when not set.
+	Interval time.Duration
+}
+
+// StatReporter is a Metrics that aggregates the calls of the clients it is passed to with
+// WithMetrics and reports them to the TARS stat service, usually tars.tarsstat.StatObj.
+type StatReporter struct {
+	config  StatConfig
+	proxy   *StatFProxy
+	mutex   sync.Mutex
+	stats   map[StatMicMsgHead]*StatMicMsgBody
+	flusher *flushLoop
+}
+
+// NewStatReporter reports to the stat servant obj and starts reporting in the background
+// until Close.
+func NewStatReporter(obj string, cfg StatConfig, opts ...ClientOption) *StatReporter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultStatInterval
+	}
+	r := &StatReporter{
+		config: cfg,
+		proxy:  NewStatFProxy(obj, 3*time.Second, opts...),
+		stats:  make(map[StatMicMsgHead]*StatMicMsgBody),
+	}
+	r.flusher = startFlushLoop(cfg.Interval, r.Report)
+	return r
+}
+
+func (r *StatReporter) CallStarted(servant, funcName string) {
+}
+
+func (r *StatReporter) CallFinished(servant, funcName string, endpoint EndpointF, code int32, latency time.Duration) {
+	head := StatMicMsgHead{
+		MasterName:    r.config.Module,
+		SlaveName:     statModule(servant),
+		InterfaceName: funcName,
+		MasterIp:      r.config.MasterIp,
+		SlaveIp:       endpoint.Host,
+		SlavePort:     endpoint.Port,
+		SlaveSetID:    endpoint.SetId,
+		ReturnValue:   code,
+	}
+	if name, area, group, ok := splitSetDivision(r.config.SetDivision); ok {
+		head.MasterName += "." + name + area + group
+	}
+	ms := int32(latency / time.Millisecond)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	body, exist := r.stats[head]
+	if !exist {
+		body = &StatMicMsgBody{IntervalCount: make(map[int32]int32), MinRspTime: ms}
+		r.stats[head] = body
+	}
+	switch code {
+	case TarsServerSuccess:
+		body.Count++
+	case TarsInvokeTimeout:
+		body.TimeoutCount++
+	default:
+		body.ExecCount++
+	}
+	interval := StatIntervalOverflow
+	for _, bound := range DefaultStatIntervals {
+		if ms <= bound {
+			interval = bound
+			break
+		}
+	}
+	body.IntervalCount[interval]++
+	body.TotalRspTime += int64(ms)
+	if ms > body.MaxRspTime {
+		body.MaxRspTime = ms
+	}
+	if ms < body.MinRspTime {
+		body.MinRspTime = ms
+	}
+}
+
+// splitSetDivision splits a set division "name.area.group" into its parts.
+func splitSetDivision(s string) (name, area, group string, ok bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// statModule turns a servant "App.Server.Obj" into the module "App.Server" stat expects.
+func statModule(servant string) string {
+	if i := strings.LastIndex(servant, "."); i > 0 {
+		return servant[:i]
+	}
+	return servant
+}
+
+// Report sends the calls aggregated since the last report.
+func (r *StatReporter) Report() {
+	r.mutex.Lock()
+	stats := r.stats
+	r.stats = make(map[StatMicMsgHead]*StatMicMsgBody)
+	r.mutex.Unlock()
+	if len(stats) == 0 {
+		return
+	}
+	msg := make(map[StatMicMsgHead]StatMicMsgBody, len(stats))
+	for head, body := range stats {
+		msg[head] = *body
+	}
+	if _, _, err := r.proxy.ReportMicMsg(msg, true, nil); nil != err {
+		logMsg(LogLevelError, "Failed to report stat", Field{"count", len(msg)}, Field{FieldError, err})
+	}
+}
+
+// Close reports the calls not reported yet and stops the reporter.
+func (r *StatReporter) Close() error {
+	r.flusher.close(r.proxy.TarsClient.Close)
+	return nil
+}
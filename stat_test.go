@@ -0,0 +1,113 @@
+package tarsgo
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// statRecorder is a stand-in StatObj keeping what was reported.
+type statRecorder struct {
+	mutex sync.Mutex
+	msgs  map[StatMicMsgHead]StatMicMsgBody
+}
+
+func (s *statRecorder) ReportMicMsg(msg map[StatMicMsgHead]StatMicMsgBody, bFromClient bool, context map[string]string) (int32, map[string]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for head, body := range msg {
+		sum := s.msgs[head]
+		sum.Count += body.Count
+		sum.TimeoutCount += body.TimeoutCount
+		sum.ExecCount += body.ExecCount
+		sum.TotalRspTime += body.TotalRspTime
+		if nil == sum.IntervalCount {
+			sum.IntervalCount = make(map[int32]int32)
+		}
+		for interval, n := range body.IntervalCount {
+			sum.IntervalCount[interval] += n
+		}
+		s.msgs[head] = sum
+	}
+	return 0, nil, nil
+}
+
+func (s *statRecorder) ReportSampleMsg(msg []StatSampleMsg, context map[string]string) (int32, map[string]string, error) {
+	return 0, nil, nil
+}
+
+func TestStatReporter(t *testing.T) {
+	recorder := &statRecorder{msgs: make(map[StatMicMsgHead]StatMicMsgBody)}
+	_, statObj := startTestServer(t, "tars.tarsstat.StatObj", &StatFDispatcher{recorder})
+	_, obj := startTestServer(t, "Test.EchoServer.EchoObj", slowDispatcher(20*time.Millisecond))
+	_, endpoints, _ := ParseProxy(obj)
+
+	r := NewStatReporter(statObj, StatConfig{Module: "Test.Caller", MasterIp: "127.0.0.1", Interval: 20 * time.Millisecond})
+	c := NewClient(obj, time.Second, WithMetrics(r))
+	defer c.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil != err {
+			t.Fatal(err)
+		}
+	}
+	short := NewClient(obj, 5*time.Millisecond, WithMetrics(r))
+	defer short.Close()
+	if _, err := short.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); err != ErrTarsRPCTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	r.CallFinished("Test.EchoServer.EchoObj", "slow", endpoints[0], TarsServerSuccess, 5*time.Second)
+	set := endpoints[0]
+	set.SetId = "app.sz.1"
+	r.CallFinished("Test.EchoServer.EchoObj", "set", set, TarsServerSuccess, time.Millisecond)
+	r.Close()
+
+	head := StatMicMsgHead{
+		MasterName:    "Test.Caller",
+		SlaveName:     "Test.EchoServer",
+		InterfaceName: "echo",
+		MasterIp:      "127.0.0.1",
+		SlaveIp:       "127.0.0.1",
+		SlavePort:     endpoints[0].Port,
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	ok := recorder.msgs[head]
+	if ok.Count != 3 || ok.TotalRspTime < 60 || ok.IntervalCount[50] != 3 {
+		t.Fatalf("unexpected stat of successful calls %+v in %+v", ok, recorder.msgs)
+	}
+	head.ReturnValue = TarsInvokeTimeout
+	if timeout := recorder.msgs[head]; timeout.TimeoutCount != 1 || timeout.Count != 0 {
+		t.Fatalf("unexpected stat of timed out calls %+v in %+v", timeout, recorder.msgs)
+	}
+	head.ReturnValue = TarsServerSuccess
+	head.InterfaceName = "slow"
+	if slow := recorder.msgs[head]; slow.IntervalCount[StatIntervalOverflow] != 1 || slow.IntervalCount[3000] != 0 {
+		t.Fatalf("unexpected stat of slow calls %+v in %+v", slow, recorder.msgs)
+	}
+	head.InterfaceName = "set"
+	head.SlaveSetID = "app.sz.1"
+	if set := recorder.msgs[head]; set.Count != 1 {
+		t.Fatalf("unexpected stat of calls into a set %+v in %+v", set, recorder.msgs)
+	}
+}
+
+func TestStatMsgCodec(t *testing.T) {
+	msg := map[StatMicMsgHead]StatMicMsgBody{
+		{MasterName: "a.b", SlaveName: "c.d", InterfaceName: "f", SlavePort: 80, ReturnValue: -1}: {Count: 1, IntervalCount: map[int32]int32{5: 1}, MinRspTime: 2},
+	}
+	var buf bytes.Buffer
+	EncodeTagMapValue(&buf, msg, 1)
+	var decoded map[StatMicMsgHead]StatMicMsgBody
+	if err := DecodeTagMapValue(&buf, &decoded, 1, true); nil != err {
+		t.Fatal(err)
+	}
+	for head, body := range decoded {
+		if head.ReturnValue != -1 || head.SlavePort != 80 || body.IntervalCount[5] != 1 || body.MinRspTime != 2 {
+			t.Fatalf("unexpected %+v %+v", head, body)
+		}
+	}
+	if len(decoded) != 1 {
+		t.Fatalf("unexpected %+v", decoded)
+	}
+}
@@ -0,0 +1,160 @@
+package tarsgo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultTLSReloadInterval = time.Second
+
+// TLSConfig configures ssl endpoints. Certificate, key and CA files are watched and
+// reloaded when they change, so certificates can be rotated without a restart.
+type TLSConfig struct {
+	// CertFile and KeyFile hold the local certificate; required on servers, and on
+	// clients talking to servers that require client certificates.
+	CertFile string
+	KeyFile  string
+	// CAFile verifies the peer; clients fall back to the system roots when it is empty.
+	CAFile string
+	// ServerName overrides the name clients verify, which defaults to the endpoint host.
+	ServerName         string
+	InsecureSkipVerify bool
+	// ClientAuth makes servers require client certificates signed by CAFile.
+	ClientAuth bool
+	// ReloadInterval limits how often the files are checked for changes, one second by default.
+	ReloadInterval time.Duration
+
+	mutex   sync.Mutex
+	checked time.Time
+	stamp   string
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+}
+
+// WithTLS sets the certificates used to dial ssl endpoints.
+func WithTLS(cfg *TLSConfig) ClientOption {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
+
+// ClientConfig returns a tls.Config for dialing serverName with the current certificates.
+func (c *TLSConfig) ClientConfig(serverName string) (*tls.Config, error) {
+	cert, pool, err := c.load()
+	if nil != err {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		RootCAs:            pool,
+		ServerName:         serverName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.ServerName != "" {
+		cfg.ServerName = c.ServerName
+	}
+	if nil != cert {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+	return cfg, nil
+}
+
+// ServerConfig returns a tls.Config that picks up reloaded certificates on every handshake.
+func (c *TLSConfig) ServerConfig() (*tls.Config, error) {
+	cert, _, err := c.load()
+	if nil != err {
+		return nil, err
+	}
+	if nil == cert {
+		return nil, fmt.Errorf("TLS server requires CertFile and KeyFile")
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool, err := c.load()
+			if nil != err {
+				return nil, err
+			}
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if c.ClientAuth {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = pool
+			}
+			return cfg, nil
+		},
+	}, nil
+}
+
+// load returns the certificate and CA pool, reading the files again if they changed.
+// A failed reload keeps the previous material.
+func (c *TLSConfig) load() (*tls.Certificate, *x509.CertPool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	interval := c.ReloadInterval
+	if interval <= 0 {
+		interval = defaultTLSReloadInterval
+	}
+	if c.stamp != "" && time.Since(c.checked) < interval {
+		return c.cert, c.pool, nil
+	}
+	c.checked = time.Now()
+	stamp, err := c.fileStamp()
+	if nil == err && stamp == c.stamp {
+		return c.cert, c.pool, nil
+	}
+	var cert *tls.Certificate
+	var pool *x509.CertPool
+	if nil == err && c.CertFile != "" {
+		var pair tls.Certificate
+		pair, err = tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		cert = &pair
+	}
+	if nil == err && c.CAFile != "" {
+		pool, err = loadCertPool(c.CAFile)
+	}
+	if nil != err {
+		if c.stamp == "" {
+			return nil, nil, err
+		}
+		logMsg(LogLevelError, "Failed to reload TLS certificates, keep using the previous ones", Field{FieldError, err})
+		return c.cert, c.pool, nil
+	}
+	c.stamp = stamp
+	c.cert = cert
+	c.pool = pool
+	return cert, pool, nil
+}
+
+func (c *TLSConfig) fileStamp() (string, error) {
+	stamp := "loaded"
+	for _, path := range []string{c.CertFile, c.KeyFile, c.CAFile} {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if nil != err {
+			return "", err
+		}
+		stamp += fmt.Sprintf(";%s:%d:%d", path, fi.ModTime().UnixNano(), fi.Size())
+	}
+	return stamp, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,146 @@
+package tarsgo
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tars test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", der)
+	return &testCA{cert, key}
+}
+
+// issue writes name.pem and name-key.pem signed by the CA.
+func (ca *testCA) issue(t *testing.T, dir string, name string, serial int64, dnsName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{dnsName},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, name+"-key.pem"), "EC PRIVATE KEY", keyDer)
+	writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+}
+
+func writePEM(t *testing.T, path string, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSTransport(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	ca.issue(t, dir, "server", 2, "echo.tars.local")
+	ca.issue(t, dir, "client", 3, "client.tars.local")
+
+	serverTLS := &TLSConfig{
+		CertFile:       filepath.Join(dir, "server.pem"),
+		KeyFile:        filepath.Join(dir, "server-key.pem"),
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		ClientAuth:     true,
+		ReloadInterval: time.Nanosecond,
+	}
+	cfg, err := serverTLS.ServerConfig()
+	if nil != err {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	s.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	go s.Serve(tls.NewListener(l, cfg))
+	defer s.Close()
+	addr := l.Addr().String()
+	obj := fmt.Sprintf("Test.EchoServer.EchoObj@ssl -h 127.0.0.1 -p %d", l.Addr().(*net.TCPAddr).Port)
+
+	clientTLS := &TLSConfig{
+		CertFile:       filepath.Join(dir, "client.pem"),
+		KeyFile:        filepath.Join(dir, "client-key.pem"),
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		ServerName:     "echo.tars.local",
+		ReloadInterval: time.Nanosecond,
+	}
+	c := NewClient(obj, time.Second, WithTLS(clientTLS))
+	resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+	if nil != err || string(resp.SBuffer) != "hello" {
+		t.Fatalf("unexpected response %v, %v", resp, err)
+	}
+
+	wrongName := &TLSConfig{CertFile: clientTLS.CertFile, KeyFile: clientTLS.KeyFile, CAFile: clientTLS.CAFile, ServerName: "other.tars.local"}
+	if _, err = NewClient(obj, 200*time.Millisecond, WithTLS(wrongName)).Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil == err {
+		t.Fatal("expected server name verification to fail")
+	}
+	noCert := &TLSConfig{CAFile: clientTLS.CAFile, ServerName: "echo.tars.local"}
+	if _, err = NewClient(obj, 200*time.Millisecond, WithTLS(noCert)).Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil); nil == err {
+		t.Fatal("expected client certificate to be required")
+	}
+
+	ca.issue(t, dir, "server", 4, "echo.tars.local")
+	clientCfg, err := clientTLS.ClientConfig("127.0.0.1")
+	if nil != err {
+		t.Fatal(err)
+	}
+	conn, err := tls.Dial("tcp", addr, clientCfg)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if serial := conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(); serial != 4 {
+		t.Fatalf("server certificate was not reloaded, serial %d", serial)
+	}
+}
@@ -0,0 +1,277 @@
+package tarsgo
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentKey is the RequestPacket.Context and Status entry carrying the trace of a
+// call, formatted as a W3C traceparent header.
+const TraceparentKey = "traceparent"
+
+var ErrInvalidTraceparent = errors.New("Invalid traceparent")
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats sc as a version 00 traceparent.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceparent parses a traceparent such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("%w:%s", ErrInvalidTraceparent, s)
+	}
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if nil != err1 || nil != err2 || nil != err3 || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return sc, fmt.Errorf("%w:%s", ErrInvalidTraceparent, s)
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("%w:%s", ErrInvalidTraceparent, s)
+	}
+	return sc, nil
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx in which calls are children of sc.
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanFromContext returns the span of the call being served or made with ctx.
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanKey{}).(SpanContext)
+	return sc, ok
+}
+
+const (
+	SpanKindClient = "client"
+	SpanKindServer = "server"
+)
+
+// Span records one call, seen from the client or from the server. Endpoint is the server
+// called by a client span and the caller of a server span.
+type Span struct {
+	TraceID      string    `json:"traceId"`
+	SpanID       string    `json:"spanId"`
+	ParentID     string    `json:"parentId,omitempty"`
+	Kind         string    `json:"kind"`
+	Servant      string    `json:"servant"`
+	Func         string    `json:"func"`
+	Endpoint     string    `json:"endpoint,omitempty"`
+	IRet         int32     `json:"iret"`
+	Error        string    `json:"error,omitempty"`
+	RequestSize  int       `json:"requestSize"`
+	ResponseSize int       `json:"responseSize"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+}
+
+// SpanExporter receives finished spans. It is called from the goroutine of the call, so
+// it should not block.
+type SpanExporter interface {
+	ExportSpan(span *Span) error
+}
+
+// Tracer propagates traces through calls and reports sampled spans to Exporter.
+type Tracer struct {
+	Exporter SpanExporter
+}
+
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{Exporter: exporter}
+}
+
+func (t *Tracer) newSpan(parent SpanContext, hasParent bool) SpanContext {
+	var sc SpanContext
+	if hasParent {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		for sc.TraceID == [16]byte{} {
+			binary.BigEndian.PutUint64(sc.TraceID[:8], rand.Uint64())
+			binary.BigEndian.PutUint64(sc.TraceID[8:], rand.Uint64())
+		}
+		sc.Sampled = true
+	}
+	for sc.SpanID == [8]byte{} {
+		binary.BigEndian.PutUint64(sc.SpanID[:], rand.Uint64())
+	}
+	return sc
+}
+
+func (t *Tracer) finish(span *Span, sc SpanContext, parent SpanContext, hasParent bool, resp *ResponsePacket, err error) {
+	if !sc.Sampled || nil == t.Exporter {
+		return
+	}
+	span.TraceID = hex.EncodeToString(sc.TraceID[:])
+	span.SpanID = hex.EncodeToString(sc.SpanID[:])
+	if hasParent {
+		span.ParentID = hex.EncodeToString(parent.SpanID[:])
+	}
+	span.End = time.Now()
+	if nil != resp {
+		span.IRet = resp.IRet
+		span.ResponseSize = len(resp.SBuffer)
+	}
+	if nil != err {
+		span.IRet = ErrorCode(err)
+		span.Error = err.Error()
+	}
+	if err := t.Exporter.ExportSpan(span); nil != err {
+		logMsg(LogLevelError, "Failed to export span", Field{FieldServant, span.Servant}, Field{FieldFunc, span.Func}, Field{FieldError, err})
+	}
+}
+
+// ClientInterceptor starts a client span for every call, as a child of the span in its
+// context, and passes it to the server in the Context and Status of the request.
+func (t *Tracer) ClientInterceptor() ClientInterceptor {
+	return func(ctx context.Context, req *RequestPacket, next Invoker) (*ResponsePacket, error) {
+		parent, hasParent := SpanFromContext(ctx)
+		sc := t.newSpan(parent, hasParent)
+		traceparent := sc.Traceparent()
+		req.Context = withEntry(req.Context, TraceparentKey, traceparent)
+		req.Status = withEntry(req.Status, TraceparentKey, traceparent)
+		span := &Span{
+			Kind:        SpanKindClient,
+			Servant:     req.SServantName,
+			Func:        req.SFuncName,
+			RequestSize: len(req.SBuffer),
+			Start:       time.Now(),
+		}
+		var peer EndpointF
+		resp, err := next(context.WithValue(ContextWithSpan(ctx, sc), peerKey{}, &peer), req)
+		if peer.Host != "" {
+			span.Endpoint = peer.String()
+		}
+		t.finish(span, sc, parent, hasParent, resp, err)
+		return resp, err
+	}
+}
+
+// ServerInterceptor continues the trace of every request in a server span whose context
+// is passed on to the servant, so that calls it makes join the trace.
+func (t *Tracer) ServerInterceptor() ServerInterceptor {
+	return func(ctx context.Context, req *RequestPacket, resp *ResponsePacket, next Handler) error {
+		traceparent, exist := req.Status[TraceparentKey]
+		if !exist {
+			traceparent, exist = req.Context[TraceparentKey]
+		}
+		var parent SpanContext
+		var hasParent bool
+		if exist {
+			var err error
+			parent, err = ParseTraceparent(traceparent)
+			hasParent = nil == err
+		}
+		sc := t.newSpan(parent, hasParent)
+		span := &Span{
+			Kind:        SpanKindServer,
+			Servant:     req.SServantName,
+			Func:        req.SFuncName,
+			RequestSize: len(req.SBuffer),
+			Start:       time.Now(),
+		}
+		if current, ok := CurrentFromContext(ctx); ok && nil != current.RemoteAddr {
+			span.Endpoint = current.RemoteAddr.String()
+		}
+		err := next(ContextWithSpan(ctx, sc), req, resp)
+		t.finish(span, sc, parent, hasParent, resp, err)
+		return err
+	}
+}
+
+// withEntry returns m with key set, copying m so that maps shared by callers are untouched.
+func withEntry(m map[string]string, key, value string) map[string]string {
+	copied := make(map[string]string, len(m)+1)
+	for k, v := range m {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// MemoryExporter keeps spans in memory, for tests.
+type MemoryExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func (e *MemoryExporter) ExportSpan(span *Span) error {
+	e.mutex.Lock()
+	e.spans = append(e.spans, span)
+	e.mutex.Unlock()
+	return nil
+}
+
+// Spans returns the spans exported so far.
+func (e *MemoryExporter) Spans() []*Span {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+func (e *MemoryExporter) Reset() {
+	e.mutex.Lock()
+	e.spans = nil
+	e.mutex.Unlock()
+}
+
+// FileExporter appends spans to a file as JSON, one per line.
+type FileExporter struct {
+	mutex sync.Mutex
+	file  *os.File
+	enc   *json.Encoder
+}
+
+func NewFileExporter(path string) (*FileExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if nil != err {
+		return nil, err
+	}
+	return &FileExporter{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (e *FileExporter) ExportSpan(span *Span) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.enc.Encode(span)
+}
+
+func (e *FileExporter) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.file.Close()
+}
@@ -0,0 +1,118 @@
+package tarsgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// forwardDispatcher passes every call on to another client with the context it got.
+type forwardDispatcher struct {
+	c *Client
+}
+
+func (d forwardDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	r, err := d.c.InvokeContext(ctx, JCENORMAL, req.SFuncName, bytes.NewBuffer(req.SBuffer), nil)
+	if nil != err {
+		return err
+	}
+	resp.SBuffer = r.SBuffer
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	exporter := new(MemoryExporter)
+	tracer := NewTracer(exporter)
+	back, backObj := startTestServer(t, "Test.BackServer.EchoObj", echoDispatcher{})
+	back.AddInterceptor(tracer.ServerInterceptor())
+	backClient := NewClient(backObj, time.Second, WithInterceptors(tracer.ClientInterceptor()))
+	defer backClient.Close()
+	front, frontObj := startTestServer(t, "Test.FrontServer.EchoObj", forwardDispatcher{backClient})
+	front.AddInterceptor(tracer.ServerInterceptor())
+	c := NewClient(frontObj, time.Second, WithInterceptors(tracer.ClientInterceptor()))
+	defer c.Close()
+
+	ctx := map[string]string{"user": "tars"}
+	if _, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), ctx); nil != err {
+		t.Fatal(err)
+	}
+	if len(ctx) != 1 {
+		t.Fatalf("caller context was modified: %v", ctx)
+	}
+	spans := exporter.Spans()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+	// spans finish from the innermost out
+	backServer, backCall, frontServer, frontCall := spans[0], spans[1], spans[2], spans[3]
+	for _, span := range spans {
+		if span.TraceID != frontCall.TraceID || span.IRet != 0 || span.RequestSize != 5 || span.ResponseSize != 5 {
+			t.Fatalf("unexpected span %+v", span)
+		}
+	}
+	if frontCall.ParentID != "" || frontServer.ParentID != frontCall.SpanID ||
+		backCall.ParentID != frontServer.SpanID || backServer.ParentID != backCall.SpanID {
+		t.Fatalf("broken span tree %+v %+v %+v %+v", frontCall, frontServer, backCall, backServer)
+	}
+	if frontCall.Kind != SpanKindClient || backServer.Kind != SpanKindServer || backServer.Servant != "Test.BackServer.EchoObj" || backServer.Func != "echo" {
+		t.Fatalf("unexpected spans %+v %+v", frontCall, backServer)
+	}
+	_, endpoints, _ := ParseProxy(backObj)
+	if backCall.Endpoint != endpoints[0].String() || backServer.Endpoint == "" {
+		t.Fatalf("unexpected endpoints %q %q", backCall.Endpoint, backServer.Endpoint)
+	}
+
+	back.AddServant("Test.BackServer.EchoObj", failingDispatcher{NewTarsError(TarsServerNoFuncErr, "missing")})
+	exporter.Reset()
+	if _, err := c.Invoke(JCENORMAL, "missing", bytes.NewBufferString("hello"), nil); ErrorCode(err) != TarsServerNoFuncErr {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, span := range exporter.Spans() {
+		if span.IRet != TarsServerNoFuncErr || span.Error == "" {
+			t.Fatalf("failure not recorded in %+v", span)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	fileExporter, err := NewFileExporter(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	for _, span := range spans {
+		fileExporter.ExportSpan(span)
+	}
+	fileExporter.Close()
+	f, err := os.Open(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	lines := 0
+	for ; scanner.Scan(); lines++ {
+		var span Span
+		if err := json.Unmarshal(scanner.Bytes(), &span); nil != err || span.SpanID != spans[lines].SpanID {
+			t.Fatalf("unexpected line %s: %v", scanner.Text(), err)
+		}
+	}
+	if lines != 4 {
+		t.Fatalf("expected 4 lines, got %d", lines)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if nil != err || !sc.Sampled || sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("unexpected %+v, %v", sc, err)
+	}
+	for _, s := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		if _, err := ParseTraceparent(s); nil == err {
+			t.Fatalf("ParseTraceparent(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tarsgo
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// maxUDPPacketSize is the largest UDP payload that fits in an IPv4 datagram.
+const maxUDPPacketSize = 65507
+
+var ErrUDPPacketTooLarge = errors.New("Packet too large for UDP")
+
+// dialEndpoint connects to e with the transport selected by e.Istcp.
+func dialEndpoint(ctx context.Context, e EndpointF, tlsConfig *TLSConfig) (net.Conn, error) {
+	var dialer net.Dialer
+	switch e.Istcp {
+	case EndpointUDP:
+		return dialer.DialContext(ctx, "udp", e.Address())
+	case EndpointSSL:
+		if nil == tlsConfig {
+			tlsConfig = &TLSConfig{}
+		}
+		cfg, err := tlsConfig.ClientConfig(e.Host)
+		if nil != err {
+			return nil, err
+		}
+		tlsDialer := tls.Dialer{Config: cfg}
+		return tlsDialer.DialContext(ctx, "tcp", e.Address())
+	case EndpointUnix:
+		return dialer.DialContext(ctx, "unix", e.Address())
+	default:
+		return dialer.DialContext(ctx, "tcp", e.Address())
+	}
+}
+
+// unpackDatagram checks that a datagram holds exactly one packet and returns a copy of its
+// body, so the read buffer can be reused.
+func unpackDatagram(b []byte) ([]byte, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("%w:datagram of %d bytes", ErrInvalidPacketLength, len(b))
+	}
+	hlen := binary.BigEndian.Uint32(b)
+	if int(hlen) != len(b) {
+		return nil, fmt.Errorf("%w:%d in datagram of %d bytes", ErrInvalidPacketLength, hlen, len(b))
+	}
+	return append([]byte(nil), b[4:]...), nil
+}
+
+func checkUDPPacketSize(packet []byte) error {
+	if len(packet) > maxUDPPacketSize {
+		return fmt.Errorf("%w:%d bytes exceeds the limit of %d", ErrUDPPacketTooLarge, len(packet), maxUDPPacketSize)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package tarsgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// echoDispatcher answers every call with the request buffer.
+type echoDispatcher struct{}
+
+func (echoDispatcher) Dispatch(ctx context.Context, req *RequestPacket, resp *ResponsePacket) error {
+	resp.SBuffer = req.SBuffer
+	return nil
+}
+
+func TestUDPTransport(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	s.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	go s.ServePacket(pc)
+	defer s.Close()
+
+	obj := fmt.Sprintf("Test.EchoServer.EchoObj@udp -h 127.0.0.1 -p %d", pc.LocalAddr().(*net.UDPAddr).Port)
+	c := NewClient(obj, time.Second)
+	for i := 0; i < 10; i++ {
+		req := []byte(fmt.Sprintf("hello %d", i))
+		resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBuffer(req), nil)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resp.SBuffer, req) {
+			t.Fatalf("unexpected response %q", resp.SBuffer)
+		}
+	}
+
+	_, err = c.Invoke(JCENORMAL, "echo", bytes.NewBuffer(make([]byte, maxUDPPacketSize)), nil)
+	if !errors.Is(err, ErrUDPPacketTooLarge) {
+		t.Fatalf("expected ErrUDPPacketTooLarge, got %v", err)
+	}
+}
+
+func TestUnixTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echo.sock")
+	l, err := Listen("unix -h " + path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	s.AddServant("Test.EchoServer.EchoObj", echoDispatcher{})
+	go s.Serve(l)
+	defer s.Close()
+
+	c := NewClient("Test.EchoServer.EchoObj@unix -h "+path, time.Second)
+	resp, err := c.Invoke(JCENORMAL, "echo", bytes.NewBufferString("hello"), nil)
+	if nil != err || string(resp.SBuffer) != "hello" {
+		t.Fatalf("unexpected response %v, %v", resp, err)
+	}
+	if _, err = Listen("unix -h " + path); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("listening on a socket in use returned %v", err)
+	}
+
+	stale := filepath.Join(t.TempDir(), "stale.sock")
+	l, err = net.Listen("unix", stale)
+	if nil != err {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if l, err = Listen("unix -h " + stale); nil != err {
+		t.Fatalf("stale socket was not replaced: %v", err)
+	}
+	l.Close()
+}